@@ -0,0 +1,387 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaxBatchGetKeys is the maximum number of keys in a single BatchGetItem request.
+const MaxBatchGetKeys = 100
+
+// RetryPolicy controls how unprocessed keys and items of batch operations are retried.
+// Delays grow exponentially from BaseDelay, up to MaxDelay. A zero BaseDelay retries without delay.
+type RetryPolicy struct {
+	// MaxAttempts The maximum number of requests made for the same keys or items, including the first one.
+	MaxAttempts int
+	// BaseDelay The delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay The maximum delay between retries.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of batch operations, unless configured otherwise with Builder.WithRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// wait sleeps before the given retry (1 for the first retry), or returns early if the context is done.
+func (p RetryPolicy) wait(ctx context.Context, retry int) error {
+	// A zero BaseDelay retries right away.
+	if p.BaseDelay <= 0 {
+		return ctx.Err()
+	}
+	shift := retry - 1
+	delay := p.BaseDelay << shift
+	if shift >= 63 || delay>>shift != p.BaseDelay || delay > p.MaxDelay {
+		// Overflowed, or too long.
+		delay = p.MaxDelay
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// BatchGetResult holds the models returned by Repository.BatchGet.
+type BatchGetResult[T Model] struct {
+	// Items The models that were found, in the order of the keys they were requested by.
+	Items []T
+	// Missing The keys that were not found, in the order they were requested.
+	Missing []Key
+	// Unmatched The items that the modeler does not support, in the order of the keys they were requested by.
+	// Only set with ReportUnmatched.
+	Unmatched []map[string]types.AttributeValue
+}
+
+// BatchGet implements Repository.
+func (r *repositoryImpl[T]) BatchGet(ctx context.Context, keys []Key, opts ...ReadOption) (*BatchGetResult[T], error) {
+	// Requests cannot contain duplicate keys, so we only request each key once.
+	unique := make([]Key, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := r.checkKey(key); err != nil {
+			return nil, err
+		}
+		id := key.id()
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, key)
+		}
+	}
+
+	o := newReadOptions(opts)
+	var keyAttributes []string
+	if len(unique) > 0 {
+		keyAttributes = unique[0].Attributes()
+	}
+	projection := r.projectedAttributes(o, keyAttributes...)
+	template := types.KeysAndAttributes{}
+	if o.consistent {
+		template.ConsistentRead = aws.Bool(true)
+	}
+	if projection != nil {
+		expr, err := expression.NewBuilder().WithProjection(projectionBuilder(projection)).Build()
+		if err != nil {
+			return nil, err
+		}
+		template.ProjectionExpression = expr.Projection()
+		template.ExpressionAttributeNames = expr.Names()
+	}
+
+	items := make(map[string]map[string]types.AttributeValue, len(unique))
+	for start := 0; start < len(unique); start += MaxBatchGetKeys {
+		end := min(start+MaxBatchGetKeys, len(unique))
+		if err := r.batchGetChunk(ctx, template, unique[start:end], items); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BatchGetResult[T]{
+		Items: make([]T, 0, len(items)),
+	}
+	for _, key := range keys {
+		item, ok := items[key.id()]
+		if !ok {
+			result.Missing = append(result.Missing, key)
+			continue
+		}
+		// Convert the item to a model.
+		model, matched, err := r.loadModel(ctx, item, projection)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			if r.unmatched == ReportUnmatched {
+				result.Unmatched = append(result.Unmatched, item)
+			}
+			continue
+		}
+		result.Items = append(result.Items, model)
+	}
+	return result, nil
+}
+
+// batchGetChunk reads up to MaxBatchGetKeys keys, retrying unprocessed keys, and collects the items by key.
+// The keys are read with the consistency and projection of the template.
+func (r *repositoryImpl[T]) batchGetChunk(ctx context.Context, template types.KeysAndAttributes, keys []Key, items map[string]map[string]types.AttributeValue) error {
+	request := &template
+	request.Keys = make([]map[string]types.AttributeValue, 0, len(keys))
+	for _, key := range keys {
+		request.Keys = append(request.Keys, key)
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := r.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{*r.tableName: *request},
+		})
+		if err != nil {
+			return err
+		}
+		for _, item := range out.Responses[*r.tableName] {
+			items[keyOf(item, keys[0]).id()] = item
+		}
+
+		unprocessed, ok := out.UnprocessedKeys[*r.tableName]
+		if !ok || len(unprocessed.Keys) == 0 {
+			return nil
+		}
+		if attempt >= r.retryPolicy.MaxAttempts {
+			return fmt.Errorf("%w: %d keys after %d attempts", ErrUnprocessed, len(unprocessed.Keys), attempt)
+		}
+		if err := r.retryPolicy.wait(ctx, attempt); err != nil {
+			return err
+		}
+		request = &unprocessed
+	}
+}
+
+// keyOf extracts the key of an item, given a key with the same attributes.
+func keyOf(item map[string]types.AttributeValue, like Key) Key {
+	key := make(Key, len(like))
+	for k := range like {
+		key[k] = item[k]
+	}
+	return key
+}
+
+// MaxBatchWriteItems is the maximum number of items in a single BatchWriteItem request.
+const MaxBatchWriteItems = 25
+
+// DefaultBatchConcurrency is how many BatchWriteItem requests are in flight at once, unless configured otherwise
+// with Builder.WithBatchConcurrency.
+const DefaultBatchConcurrency = 4
+
+// BatchWriteReport reports the outcome of every item of Repository.BatchPut or Repository.BatchDelete.
+type BatchWriteReport struct {
+	// Results The outcome of each item, in the order the items were given.
+	Results []BatchWriteResult
+}
+
+// BatchWriteResult is the outcome of a single item of a batch write.
+type BatchWriteResult struct {
+	// Key The key of the item.
+	Key Key
+	// Err Why the item was not written. Nil if it was.
+	Err error
+}
+
+// Failed returns the results of the items that were not written.
+func (r *BatchWriteReport) Failed() []BatchWriteResult {
+	var failed []BatchWriteResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns the errors of the items that were not written, joined. Nil if all items were written.
+func (r *BatchWriteReport) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("%v: %w", result.Key, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// batchWriteRequest is an item of a batch write, along with the model it was derived from, if any.
+type batchWriteRequest struct {
+	key     Key
+	model   Model
+	request types.WriteRequest
+}
+
+// BatchPut implements Repository.
+func (r *repositoryImpl[T]) BatchPut(ctx context.Context, models []T) (*BatchWriteReport, error) {
+	requests := make([]batchWriteRequest, len(models))
+	report := &BatchWriteReport{Results: make([]BatchWriteResult, len(models))}
+	for i, model := range models {
+		key := model.Key()
+		report.Results[i].Key = key
+		if err := r.checkKey(key); err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		// Putting a partial model would overwrite the attributes that were not read.
+		if t, ok := Model(model).(tracker); ok && t.Partial() {
+			report.Results[i].Err = ErrPartialModel
+			continue
+		}
+		putItem, err := r.constructPutItem(model)
+		if err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		requests[i] = batchWriteRequest{
+			key:     key,
+			model:   model,
+			request: types.WriteRequest{PutRequest: &types.PutRequest{Item: putItem.Item}},
+		}
+	}
+	r.batchWrite(ctx, requests, report)
+	return report, report.Err()
+}
+
+// BatchDelete implements Repository.
+func (r *repositoryImpl[T]) BatchDelete(ctx context.Context, keys []Key) (*BatchWriteReport, error) {
+	requests := make([]batchWriteRequest, len(keys))
+	report := &BatchWriteReport{Results: make([]BatchWriteResult, len(keys))}
+	for i, key := range keys {
+		report.Results[i].Key = key
+		if err := r.checkKey(key); err != nil {
+			report.Results[i].Err = err
+			continue
+		}
+		requests[i] = batchWriteRequest{
+			key:     key,
+			request: types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}},
+		}
+	}
+	r.batchWrite(ctx, requests, report)
+	return report, report.Err()
+}
+
+// batchWrite writes the requests in chunks of MaxBatchWriteItems, with bounded concurrency, and records the outcome
+// of each request in the report. Requests without a key were already reported as failed, and are skipped.
+func (r *repositoryImpl[T]) batchWrite(ctx context.Context, requests []batchWriteRequest, report *BatchWriteReport) {
+	// Requests cannot contain the same key twice. The last write of a key wins, as it would if written in sequence.
+	last := make(map[string]int, len(requests))
+	for i, req := range requests {
+		if req.key != nil {
+			last[req.key.id()] = i
+		}
+	}
+	var indexes []int
+	for i, req := range requests {
+		if req.key != nil && last[req.key.id()] == i {
+			indexes = append(indexes, i)
+		}
+	}
+
+	concurrency := r.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for start := 0; start < len(indexes); start += MaxBatchWriteItems {
+		chunk := indexes[start:min(start+MaxBatchWriteItems, len(indexes))]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// Each chunk only records the outcomes of its own requests, so no locking is needed.
+			r.batchWriteChunk(ctx, requests, chunk, report)
+		}()
+	}
+	wg.Wait()
+
+	// Earlier writes of a duplicated key share the outcome of the last one.
+	for i, req := range requests {
+		if req.key != nil {
+			if j := last[req.key.id()]; j != i {
+				report.Results[i].Err = report.Results[j].Err
+			}
+		}
+	}
+}
+
+// batchWriteChunk writes up to MaxBatchWriteItems requests, retrying unprocessed items.
+func (r *repositoryImpl[T]) batchWriteChunk(ctx context.Context, requests []batchWriteRequest, chunk []int, report *BatchWriteReport) {
+	pending := make(map[string]int, len(chunk))
+	writeRequests := make([]types.WriteRequest, 0, len(chunk))
+	for _, i := range chunk {
+		pending[requests[i].key.id()] = i
+		writeRequests = append(writeRequests, requests[i].request)
+	}
+	like := requests[chunk[0]].key
+
+	fail := func(err error) {
+		for _, i := range pending {
+			report.Results[i].Err = err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		out, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{*r.tableName: writeRequests},
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		unprocessed := out.UnprocessedItems[*r.tableName]
+		still := make(map[string]int, len(unprocessed))
+		for _, req := range unprocessed {
+			var id string
+			if req.PutRequest != nil {
+				id = keyOf(req.PutRequest.Item, like).id()
+			} else if req.DeleteRequest != nil {
+				id = Key(req.DeleteRequest.Key).id()
+			}
+			if i, ok := pending[id]; ok {
+				still[id] = i
+			}
+		}
+		// Everything else was written.
+		for id, i := range pending {
+			if _, ok := still[id]; !ok {
+				if model := requests[i].model; model != nil {
+					track(model, requests[i].request.PutRequest.Item)
+				}
+			}
+		}
+		pending = still
+		if len(pending) == 0 {
+			return
+		}
+		if attempt >= r.retryPolicy.MaxAttempts {
+			fail(fmt.Errorf("%w: after %d attempts", ErrUnprocessed, attempt))
+			return
+		}
+		if err := r.retryPolicy.wait(ctx, attempt); err != nil {
+			fail(err)
+			return
+		}
+		writeRequests = unprocessed
+	}
+}
@@ -1,21 +1,45 @@
 package dynamorm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type Builder[T Model] struct {
-	client    *dynamodb.Client
+	client    DynamoDBAPI
 	tableName string
 	modeler   func(item map[string]types.AttributeValue) (T, error)
+	indexes   map[string]Index
+	required  []string
+	version   string
+	maxDepth  int
+	related   map[reflect.Type]string
+	chunked   bool
+	retry     RetryPolicy
+	batchConc int
+	keySchema *KeySchema
+	tokens    func(ctx context.Context) string
+	unmatched UnmatchedPolicy
 }
 
 func NewBuilder[T Model]() *Builder[T] {
-	return &Builder[T]{}
+	return &Builder[T]{
+		maxDepth:  DefaultMaxRelatedDepth,
+		retry:     DefaultRetryPolicy,
+		batchConc: DefaultBatchConcurrency,
+	}
 }
 
-func (b *Builder[T]) WithClient(client *dynamodb.Client) *Builder[T] {
+func (b *Builder[T]) WithClient(client DynamoDBAPI) *Builder[T] {
 	b.client = client
 	return b
 }
@@ -25,16 +49,332 @@ func (b *Builder[T]) WithTableName(tableName string) *Builder[T] {
 	return b
 }
 
+// WithModeler sets the function that converts items into models.
+// When not set, StructModeler is used, which suits models that are pointers to structs tagged with `dynamodbav`.
 func (b *Builder[T]) WithModeler(modeler func(item map[string]types.AttributeValue) (T, error)) *Builder[T] {
 	b.modeler = modeler
 	return b
 }
 
+// WithIndex declares a secondary index of the table, so that it can be queried with Query.OnIndex.
+func (b *Builder[T]) WithIndex(index Index) *Builder[T] {
+	if b.indexes == nil {
+		b.indexes = make(map[string]Index)
+	}
+	b.indexes[index.Name] = index
+	return b
+}
+
+// WithRequiredAttributes declares the attributes that the modeler needs in order to construct a model.
+// Items read from indexes that do not project all attributes are checked against these before being modeled.
+func (b *Builder[T]) WithRequiredAttributes(attributes ...string) *Builder[T] {
+	b.required = append(b.required, attributes...)
+	return b
+}
+
+// WithVersionAttribute enables optimistic locking using the given attribute, which holds a number that is
+// incremented on every Create and Update. See Versioned for details.
+func (b *Builder[T]) WithVersionAttribute(attribute string) *Builder[T] {
+	b.version = attribute
+	return b
+}
+
+// WithMaxRelatedDepth sets how many levels of related models are walked when saving or deleting a model.
+// Defaults to DefaultMaxRelatedDepth. Models nested deeper fail with ErrRelatedTooDeep.
+func (b *Builder[T]) WithMaxRelatedDepth(depth int) *Builder[T] {
+	b.maxDepth = depth
+	return b
+}
+
+// WithRelatedTable registers the table that related models of the same type as model are written to,
+// for related models that do not implement TableNamer.
+func (b *Builder[T]) WithRelatedTable(model Model, tableName string) *Builder[T] {
+	if b.related == nil {
+		b.related = make(map[reflect.Type]string)
+	}
+	b.related[reflect.TypeOf(model)] = tableName
+	return b
+}
+
+// WithChunkedTransactions opts into splitting writes that exceed the limits of a single transaction
+// (MaxTransactItems items or MaxTransactSize bytes) into several transactions, which are compensated for
+// should one of them fail. See SagaError.
+//
+// Note that this gives up on atomicity: the intermediate state is visible to other readers and writers.
+// Without it, such writes fail up front with ErrTransactionTooLarge.
+func (b *Builder[T]) WithChunkedTransactions() *Builder[T] {
+	b.chunked = true
+	return b
+}
+
+// WithRetryPolicy sets how unprocessed keys and items of batch operations are retried.
+// Defaults to DefaultRetryPolicy.
+func (b *Builder[T]) WithRetryPolicy(policy RetryPolicy) *Builder[T] {
+	b.retry = policy
+	return b
+}
+
+// WithBatchConcurrency sets how many BatchWriteItem requests BatchPut and BatchDelete have in flight at once.
+// Defaults to DefaultBatchConcurrency.
+func (b *Builder[T]) WithBatchConcurrency(concurrency int) *Builder[T] {
+	b.batchConc = concurrency
+	return b
+}
+
+// WithKeySchema sets the names and types of the key attributes of the table.
+// Keys are then checked against it before any request is sent, failing with ErrInvalidKey if they do not conform,
+// and BuildAndVerify checks it against the table instead of inferring the key attributes from the model.
+func (b *Builder[T]) WithKeySchema(schema KeySchema) *Builder[T] {
+	b.keySchema = &schema
+	return b
+}
+
+// WithIdempotencyTokenProvider sets a function that derives the idempotency token of writes from their context,
+// e.g. from the request ID of a Lambda invocation, for writes whose context has no token bound with
+// WithIdempotencyToken. An empty token leaves the write without one.
+//
+// Note that a token is only valid for a single request: a provider should not derive the same token for two
+// different writes, which would fail with ErrIdempotentParameterMismatch.
+func (b *Builder[T]) WithIdempotencyTokenProvider(provider func(ctx context.Context) string) *Builder[T] {
+	b.tokens = provider
+	return b
+}
+
+// WithUnmatchedItems sets how BatchGet and Query handle the items that the modeler does not support, i.e. for which
+// it fails with IncompatibleModelerError: they fail, skip the items, or report them. Defaults to FailUnmatched.
+// Get always fails, as it has nothing else to return.
+func (b *Builder[T]) WithUnmatchedItems(policy UnmatchedPolicy) *Builder[T] {
+	b.unmatched = policy
+	return b
+}
+
+// Build validates the configuration and builds the repository.
+// Fails with ErrInvalidConfiguration, describing every problem found, if the configuration is incomplete.
 func (b *Builder[T]) Build() (Repository[T], error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
 	tableName := &b.tableName
+	modeler := b.modeler
+	if modeler == nil {
+		modeler = StructModeler[T]()
+	}
+	// Operations without a model at hand need the key and version attributes of the models, which are only
+	// resolved once rather than by calling the methods of a zero-value model on every request.
+	modelVersionAttr, err := zeroModelVersionAttribute[T](b.version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfiguration, err)
+	}
 	return &repositoryImpl[T]{
-		client:    b.client,
-		tableName: tableName,
-		modeler:   b.modeler,
+		client:             b.client,
+		tableName:          tableName,
+		modeler:            modeler,
+		indexes:            b.indexes,
+		required:           b.required,
+		versionAttr:        b.version,
+		modelVersionAttr:   modelVersionAttr,
+		modelKeyAttributes: zeroModelKey[T]().Attributes(),
+		maxRelatedDepth:    b.maxDepth,
+		relatedTables:      b.related,
+		chunked:            b.chunked,
+		retryPolicy:        b.retry,
+		batchConcurrency:   b.batchConc,
+		keySchema:          b.keySchema,
+		tokenProvider:      b.tokens,
+		unmatched:          b.unmatched,
 	}, nil
 }
+
+// BuildAndVerify builds the repository like Build, then checks it against the table using DescribeTable:
+// the table must exist, its key schema must match the attributes of the model's Key(), and the indexes declared
+// with WithIndex must exist with the same key schema.
+//
+// The key attributes of the model are taken from the key schema set with WithKeySchema, whose types are checked too.
+// Without one, they are taken from the Key() of a zero-value model, which suits models whose key is derived from
+// struct tags. They are not checked for models whose Key() cannot be called on a zero value.
+//
+// Fails with ErrTableNotFound if the table does not exist, and with ErrSchemaMismatch if it does not match.
+func (b *Builder[T]) BuildAndVerify(ctx context.Context) (Repository[T], error) {
+	repo, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := b.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: &b.tableName})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, fmt.Errorf("%w: %s: %w", ErrTableNotFound, b.tableName, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	table := out.Table
+
+	var problems []string
+	tablePK, tableSK := keySchemaAttributes(table.KeySchema)
+	if b.keySchema != nil {
+		pk, sk := b.keySchema.PartitionKey.Name, b.keySchema.SortKey.Name
+		if pk != tablePK || sk != tableSK {
+			problems = append(problems, fmt.Sprintf("the key schema is %s, but the key of the table is %s",
+				describeKey(pk, sk), describeKey(tablePK, tableSK)))
+		}
+		attrTypes := attributeDefinitionTypes(table.AttributeDefinitions)
+		for _, attr := range b.keySchema.attributes() {
+			if typ, ok := attrTypes[attr.Name]; ok && typ != attr.Type {
+				problems = append(problems, fmt.Sprintf("key attribute %q is declared of type %s, but its type is %s",
+					attr.Name, attr.Type, typ))
+			}
+		}
+	} else if pk, sk := zeroModelKeyAttributes[T](); pk != "" {
+		if pk != tablePK || sk != tableSK {
+			problems = append(problems, fmt.Sprintf("the key of the model is %s, but the key of the table is %s",
+				describeKey(pk, sk), describeKey(tablePK, tableSK)))
+		}
+	}
+
+	indexes := map[string][]types.KeySchemaElement{}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		indexes[aws.ToString(gsi.IndexName)] = gsi.KeySchema
+	}
+	for _, lsi := range table.LocalSecondaryIndexes {
+		indexes[aws.ToString(lsi.IndexName)] = lsi.KeySchema
+	}
+	for _, name := range b.indexNames() {
+		index := b.indexes[name]
+		schema, ok := indexes[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("index %q does not exist", name))
+			continue
+		}
+		if pk, sk := keySchemaAttributes(schema); pk != index.PartitionKey || sk != index.SortKey {
+			problems = append(problems, fmt.Sprintf("index %q is declared with key %s, but its key is %s",
+				name, describeKey(index.PartitionKey, index.SortKey), describeKey(pk, sk)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: table %s: %s", ErrSchemaMismatch, b.tableName, strings.Join(problems, "; "))
+	}
+	return repo, nil
+}
+
+// validate reports every problem with the configuration at once.
+func (b *Builder[T]) validate() error {
+	var problems []string
+	if b.client == nil {
+		problems = append(problems, "a client is required (WithClient)")
+	}
+	if b.tableName == "" {
+		problems = append(problems, "a table name is required (WithTableName)")
+	}
+	if typ := reflect.TypeOf((*T)(nil)).Elem(); b.modeler == nil && (typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct) {
+		problems = append(problems, fmt.Sprintf("a modeler is required for %v, which is not a pointer to a struct (WithModeler)", typ))
+	}
+	for _, name := range b.indexNames() {
+		index := b.indexes[name]
+		if index.Name == "" {
+			problems = append(problems, "indexes require a name (WithIndex)")
+		} else if index.PartitionKey == "" {
+			problems = append(problems, fmt.Sprintf("index %q requires a partition key (WithIndex)", index.Name))
+		}
+	}
+	if b.maxDepth < 0 {
+		problems = append(problems, "the max related depth cannot be negative (WithMaxRelatedDepth)")
+	}
+	if b.retry.MaxAttempts < 1 || b.retry.BaseDelay < 0 || b.retry.MaxDelay < 0 {
+		problems = append(problems, "the retry policy requires at least one attempt and non-negative delays (WithRetryPolicy)")
+	}
+	if b.batchConc < 1 {
+		problems = append(problems, "the batch concurrency must be at least 1 (WithBatchConcurrency)")
+	}
+	if b.unmatched < FailUnmatched || b.unmatched > ReportUnmatched {
+		problems = append(problems, fmt.Sprintf("unknown unmatched items policy %d (WithUnmatchedItems)", b.unmatched))
+	}
+	if b.keySchema != nil {
+		problems = append(problems, b.keySchema.problems()...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfiguration, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// indexNames returns the names of the declared indexes, sorted.
+func (b *Builder[T]) indexNames() []string {
+	names := make([]string, 0, len(b.indexes))
+	for name := range b.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// zeroModelKeyAttributes returns the names of the key attributes of a zero-value model, partition key first.
+// Returns empty names if T is not a pointer to a struct, or if its Key() cannot be called on a zero value.
+func zeroModelKeyAttributes[T Model]() (pk, sk string) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	key := zeroModelKey[T]()
+	if len(key) == 0 {
+		return "", ""
+	}
+	if fields := structKeyFieldsOf(typ.Elem()); len(fields) == len(key) {
+		// Struct tags tell which attribute is which.
+		pk = fields[0].attribute
+		if len(fields) == 2 {
+			sk = fields[1].attribute
+		}
+		return pk, sk
+	}
+	if len(key) == 1 {
+		for attr := range key {
+			pk = attr
+		}
+		return pk, ""
+	}
+	// Otherwise, which attribute is the partition key cannot be told apart.
+	return "", ""
+}
+
+// zeroModelKey returns the key of a zero-value model. Returns nil if T is not a pointer to a struct, or if its Key()
+// cannot be called on a zero value.
+func zeroModelKey[T Model]() (key Key) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			key = nil
+		}
+	}()
+	return reflect.New(typ.Elem()).Interface().(T).Key()
+}
+
+// keySchemaAttributes returns the names of the attributes of a key schema, partition key first.
+func keySchemaAttributes(schema []types.KeySchemaElement) (pk, sk string) {
+	for _, e := range schema {
+		switch e.KeyType {
+		case types.KeyTypeHash:
+			pk = aws.ToString(e.AttributeName)
+		case types.KeyTypeRange:
+			sk = aws.ToString(e.AttributeName)
+		}
+	}
+	return pk, sk
+}
+
+// attributeDefinitionTypes returns the types of the attributes of a table description, by name.
+func attributeDefinitionTypes(definitions []types.AttributeDefinition) map[string]types.ScalarAttributeType {
+	byName := make(map[string]types.ScalarAttributeType, len(definitions))
+	for _, d := range definitions {
+		byName[aws.ToString(d.AttributeName)] = d.AttributeType
+	}
+	return byName
+}
+
+func describeKey(pk, sk string) string {
+	if sk == "" {
+		return fmt.Sprintf("(%s)", pk)
+	}
+	return fmt.Sprintf("(%s, %s)", pk, sk)
+}
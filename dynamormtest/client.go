@@ -0,0 +1,787 @@
+// Package dynamormtest provides an in-memory implementation of dynamorm.DynamoDBAPI, for tests that exercise the
+// behavior of repositories rather than the exact requests they make.
+//
+// The fake honors the key schema of its tables, condition, filter, key condition, update and projection expressions,
+// transactions, queries and secondary indexes. It does not model capacity, throttling nor item size limits:
+// batch operations never leave unprocessed keys or items.
+package dynamormtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/bezhermoso/dynamorm"
+)
+
+// Table describes a table of the fake.
+type Table struct {
+	// Name The name of the table.
+	Name string
+	// PartitionKey The name of the partition key attribute.
+	PartitionKey string
+	// SortKey The name of the sort key attribute, if any.
+	SortKey string
+	// PartitionKeyType The type of the partition key attribute. Any of S, N and B is accepted if not set.
+	PartitionKeyType types.ScalarAttributeType
+	// SortKeyType The type of the sort key attribute. Any of S, N and B is accepted if not set.
+	SortKeyType types.ScalarAttributeType
+	// Indexes The secondary indexes of the table. Indexes that project KEYS_ONLY or INCLUDE only hold the key
+	// attributes of the table and of the index.
+	Indexes []dynamorm.Index
+}
+
+// Client is an in-memory implementation of dynamorm.DynamoDBAPI. It is safe for concurrent use.
+type Client struct {
+	mu     sync.Mutex
+	tables map[string]*table
+	// tokens The items of the successful transactions that were sent with a ClientRequestToken, by token.
+	// Unlike DynamoDB's, tokens do not expire.
+	tokens map[string][]types.TransactWriteItem
+}
+
+// table is a table of the fake, and its items by key.
+type table struct {
+	Table
+	items map[string]item
+}
+
+var _ dynamorm.DynamoDBAPI = &Client{}
+
+// NewClient creates a fake with the given tables, which are empty.
+func NewClient(tables ...Table) *Client {
+	c := &Client{tables: map[string]*table{}, tokens: map[string][]types.TransactWriteItem{}}
+	for _, t := range tables {
+		c.CreateTable(t)
+	}
+	return c
+}
+
+// CreateTable adds an empty table to the fake, replacing any table with the same name.
+func (c *Client) CreateTable(t Table) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tables[t.Name] = &table{Table: t, items: map[string]item{}}
+}
+
+// Item returns the item with the given key, or nil if there is none.
+func (c *Client) Item(tableName string, key dynamorm.Key) map[string]types.AttributeValue {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return copyItem(t.items[key.String()])
+}
+
+// Items returns all the items of a table, ordered by key.
+func (c *Client) Items(tableName string) []map[string]types.AttributeValue {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tables[tableName]
+	if !ok {
+		return nil
+	}
+	items := make([]map[string]types.AttributeValue, 0, len(t.items))
+	for _, it := range t.items {
+		items = append(items, copyItem(it))
+	}
+	t.sort(items, t.PartitionKey, t.SortKey)
+	return items
+}
+
+// GetItem implements dynamorm.DynamoDBAPI.
+func (c *Client) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	it, err := c.get(params.TableName, params.Key, params.ProjectionExpression, params.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: it}, nil
+}
+
+// PutItem implements dynamorm.DynamoDBAPI.
+func (c *Client) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, err := c.preparePut(params.TableName, params.Item, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if !w.ok {
+		return nil, conditionalCheckFailed(w, params.ReturnValuesOnConditionCheckFailure)
+	}
+	w.apply()
+	out := &dynamodb.PutItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = copyItem(w.old)
+	}
+	return out, nil
+}
+
+// UpdateItem implements dynamorm.DynamoDBAPI.
+func (c *Client) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, err := c.prepareUpdate(params.TableName, params.Key, params.UpdateExpression, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if !w.ok {
+		return nil, conditionalCheckFailed(w, params.ReturnValuesOnConditionCheckFailure)
+	}
+	w.apply()
+	out := &dynamodb.UpdateItemOutput{}
+	switch params.ReturnValues {
+	case types.ReturnValueAllOld:
+		out.Attributes = copyItem(w.old)
+	case types.ReturnValueAllNew:
+		out.Attributes = copyItem(w.new)
+	case types.ReturnValueUpdatedOld:
+		out.Attributes = changedAttributes(w.old, w.new, w.old)
+	case types.ReturnValueUpdatedNew:
+		out.Attributes = changedAttributes(w.old, w.new, w.new)
+	}
+	return out, nil
+}
+
+// DeleteItem implements dynamorm.DynamoDBAPI.
+func (c *Client) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, err := c.prepareDelete(params.TableName, params.Key, params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if !w.ok {
+		return nil, conditionalCheckFailed(w, params.ReturnValuesOnConditionCheckFailure)
+	}
+	w.apply()
+	out := &dynamodb.DeleteItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld {
+		out.Attributes = copyItem(w.old)
+	}
+	return out, nil
+}
+
+// Query implements dynamorm.DynamoDBAPI.
+// Results are paginated by Limit only: LastEvaluatedKey is set when the limit was reached before the last item.
+func (c *Client) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, err := c.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key attributes of what is queried, and of the items it holds.
+	pk, sk := t.PartitionKey, t.SortKey
+	keyAttributes := []string{t.PartitionKey, t.SortKey}
+	var index *dynamorm.Index
+	if params.IndexName != nil {
+		i := slices.IndexFunc(t.Indexes, func(idx dynamorm.Index) bool { return idx.Name == *params.IndexName })
+		if i < 0 {
+			return nil, validationError("The table does not have the specified index: %s", *params.IndexName)
+		}
+		index = &t.Indexes[i]
+		pk, sk = index.PartitionKey, index.SortKey
+		keyAttributes = append(keyAttributes, pk, sk)
+		// Indexes of the fake are global secondary indexes.
+		if aws.ToBool(params.ConsistentRead) {
+			return nil, validationError("Consistent reads are not supported on global secondary indexes")
+		}
+	}
+
+	p := newPlaceholders(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	if params.KeyConditionExpression == nil {
+		return nil, validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	keyCondition, err := parseCondition(*params.KeyConditionExpression, p)
+	if err != nil {
+		return nil, err
+	}
+	if !constrainsPartitionKey(keyCondition, pk) {
+		return nil, validationError("Query condition missed key schema element: %s", pk)
+	}
+	var filter condition
+	if params.FilterExpression != nil {
+		if filter, err = parseCondition(*params.FilterExpression, p); err != nil {
+			return nil, err
+		}
+	}
+	var projection []path
+	if params.ProjectionExpression != nil {
+		if projection, err = parseProjection(*params.ProjectionExpression, p); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	// Items that lack the key attributes of an index are not in it.
+	var candidates []item
+	for _, it := range t.items {
+		if index != nil {
+			if _, ok := it[pk]; !ok {
+				continue
+			}
+			if _, ok := it[sk]; sk != "" && !ok {
+				continue
+			}
+			if index.Projection != "" && index.Projection != types.ProjectionTypeAll {
+				it = projectAttributes(it, keyAttributes)
+			}
+		}
+		if keyCondition.eval(it) {
+			candidates = append(candidates, it)
+		}
+	}
+	t.sort(candidates, pk, sk)
+	if params.ScanIndexForward != nil && !*params.ScanIndexForward {
+		slices.Reverse(candidates)
+	}
+
+	// Resume after the last evaluated item of the previous page.
+	if params.ExclusiveStartKey != nil {
+		start := dynamorm.Key(params.ExclusiveStartKey).String()
+		i := slices.IndexFunc(candidates, func(it item) bool {
+			return dynamorm.Key(projectAttributes(it, keyAttributes)).String() == start
+		})
+		if i < 0 {
+			return nil, validationError("The provided starting key is invalid")
+		}
+		candidates = candidates[i+1:]
+	}
+
+	out := &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{}}
+	for i, it := range candidates {
+		if params.Limit != nil && i >= int(*params.Limit) {
+			out.LastEvaluatedKey = projectAttributes(candidates[i-1], keyAttributes)
+			break
+		}
+		out.ScannedCount++
+		if filter != nil && !filter.eval(it) {
+			continue
+		}
+		if projection != nil {
+			it = project(it, projection)
+		}
+		out.Items = append(out.Items, copyItem(it))
+		out.Count++
+	}
+	return out, nil
+}
+
+// BatchGetItem implements dynamorm.DynamoDBAPI. All keys are always processed.
+func (c *Client) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{}}
+	count := 0
+	for tableName, request := range params.RequestItems {
+		seen := map[string]bool{}
+		out.Responses[tableName] = []map[string]types.AttributeValue{}
+		for _, key := range request.Keys {
+			id := dynamorm.Key(key).String()
+			if seen[id] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+			count++
+			it, err := c.get(aws.String(tableName), key, request.ProjectionExpression, request.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+			if it != nil {
+				out.Responses[tableName] = append(out.Responses[tableName], it)
+			}
+		}
+	}
+	if count > dynamorm.MaxBatchGetKeys {
+		return nil, validationError("Too many items requested for the BatchGetItem call")
+	}
+	return out, nil
+}
+
+// BatchWriteItem implements dynamorm.DynamoDBAPI. All items are always processed.
+func (c *Client) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var writes []*pendingWrite
+	seen := map[string]bool{}
+	for tableName, requests := range params.RequestItems {
+		for _, request := range requests {
+			var w *pendingWrite
+			var err error
+			switch {
+			case request.PutRequest != nil:
+				w, err = c.preparePut(aws.String(tableName), request.PutRequest.Item, nil, nil, nil)
+			case request.DeleteRequest != nil:
+				w, err = c.prepareDelete(aws.String(tableName), request.DeleteRequest.Key, nil, nil, nil)
+			default:
+				err = validationError("Write request must have a PutRequest or a DeleteRequest")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if seen[w.target()] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[w.target()] = true
+			writes = append(writes, w)
+		}
+	}
+	if len(writes) > dynamorm.MaxBatchWriteItems {
+		return nil, validationError("Too many items requested for the BatchWriteItem call")
+	}
+	for _, w := range writes {
+		w.apply()
+	}
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}, nil
+}
+
+// TransactGetItems implements dynamorm.DynamoDBAPI.
+func (c *Client) TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(params.TransactItems) > dynamorm.MaxTransactItems {
+		return nil, validationError("Member must have length less than or equal to %d", dynamorm.MaxTransactItems)
+	}
+	out := &dynamodb.TransactGetItemsOutput{}
+	for _, ti := range params.TransactItems {
+		if ti.Get == nil {
+			return nil, validationError("TransactItems can only contain Get operations")
+		}
+		it, err := c.get(ti.Get.TableName, ti.Get.Key, ti.Get.ProjectionExpression, ti.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		out.Responses = append(out.Responses, types.ItemResponse{Item: it})
+	}
+	return out, nil
+}
+
+// TransactWriteItems implements dynamorm.DynamoDBAPI.
+// The transaction is canceled if any condition fails, with a cancellation reason for every item.
+func (c *Client) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(params.TransactItems) > dynamorm.MaxTransactItems {
+		return nil, validationError("Member must have length less than or equal to %d", dynamorm.MaxTransactItems)
+	}
+	token := aws.ToString(params.ClientRequestToken)
+	if prior, ok := c.tokens[token]; ok && token != "" {
+		// A retry of a successful transaction succeeds without being applied again.
+		if !reflect.DeepEqual(prior, params.TransactItems) {
+			return nil, &types.IdempotentParameterMismatchException{
+				Message: aws.String("Request parameters do not match the prior request with the same client token"),
+			}
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, nil
+	}
+
+	writes := make([]*pendingWrite, 0, len(params.TransactItems))
+	returnValues := make([]types.ReturnValuesOnConditionCheckFailure, 0, len(params.TransactItems))
+	seen := map[string]bool{}
+	for _, ti := range params.TransactItems {
+		var w *pendingWrite
+		var rv types.ReturnValuesOnConditionCheckFailure
+		var err error
+		switch {
+		case ti.Put != nil:
+			rv = ti.Put.ReturnValuesOnConditionCheckFailure
+			w, err = c.preparePut(ti.Put.TableName, ti.Put.Item, ti.Put.ConditionExpression, ti.Put.ExpressionAttributeNames, ti.Put.ExpressionAttributeValues)
+		case ti.Update != nil:
+			rv = ti.Update.ReturnValuesOnConditionCheckFailure
+			w, err = c.prepareUpdate(ti.Update.TableName, ti.Update.Key, ti.Update.UpdateExpression, ti.Update.ConditionExpression, ti.Update.ExpressionAttributeNames, ti.Update.ExpressionAttributeValues)
+		case ti.Delete != nil:
+			rv = ti.Delete.ReturnValuesOnConditionCheckFailure
+			w, err = c.prepareDelete(ti.Delete.TableName, ti.Delete.Key, ti.Delete.ConditionExpression, ti.Delete.ExpressionAttributeNames, ti.Delete.ExpressionAttributeValues)
+		case ti.ConditionCheck != nil:
+			rv = ti.ConditionCheck.ReturnValuesOnConditionCheckFailure
+			w, err = c.prepareConditionCheck(ti.ConditionCheck.TableName, ti.ConditionCheck.Key, ti.ConditionCheck.ConditionExpression, ti.ConditionCheck.ExpressionAttributeNames, ti.ConditionCheck.ExpressionAttributeValues)
+		default:
+			err = validationError("TransactItems must have one of Put, Update, Delete or ConditionCheck")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[w.target()] {
+			return nil, validationError("Transaction request cannot include multiple operations on one item")
+		}
+		seen[w.target()] = true
+		writes = append(writes, w)
+		returnValues = append(returnValues, rv)
+	}
+
+	reasons := make([]types.CancellationReason, len(writes))
+	canceled := false
+	codes := make([]string, len(writes))
+	for i, w := range writes {
+		reasons[i].Code = aws.String("None")
+		if !w.ok {
+			canceled = true
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed"),
+			}
+			if returnValues[i] == types.ReturnValuesOnConditionCheckFailureAllOld {
+				reasons[i].Item = copyItem(w.old)
+			}
+		}
+		codes[i] = *reasons[i].Code
+	}
+	if canceled {
+		return nil, &types.TransactionCanceledException{
+			Message:             aws.String(fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for specific reasons %v", codes)),
+			CancellationReasons: reasons,
+		}
+	}
+	for _, w := range writes {
+		w.apply()
+	}
+	if token != "" {
+		c.tokens[token] = params.TransactItems
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// DescribeTable implements dynamorm.DynamoDBAPI. Indexes are all described as global secondary indexes.
+func (c *Client) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, err := c.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	desc := &types.TableDescription{
+		TableName:   aws.String(t.Name),
+		TableStatus: types.TableStatusActive,
+		KeySchema:   keySchema(t.PartitionKey, t.SortKey),
+		ItemCount:   aws.Int64(int64(len(t.items))),
+	}
+	for _, attr := range []struct {
+		name string
+		typ  types.ScalarAttributeType
+	}{{t.PartitionKey, t.PartitionKeyType}, {t.SortKey, t.SortKeyType}} {
+		if attr.name != "" && attr.typ != "" {
+			desc.AttributeDefinitions = append(desc.AttributeDefinitions, types.AttributeDefinition{
+				AttributeName: aws.String(attr.name),
+				AttributeType: attr.typ,
+			})
+		}
+	}
+	for _, index := range t.Indexes {
+		projection := index.Projection
+		if projection == "" {
+			projection = types.ProjectionTypeAll
+		}
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   aws.String(index.Name),
+			IndexStatus: types.IndexStatusActive,
+			KeySchema:   keySchema(index.PartitionKey, index.SortKey),
+			Projection:  &types.Projection{ProjectionType: projection},
+		})
+	}
+	return &dynamodb.DescribeTableOutput{Table: desc}, nil
+}
+
+func keySchema(pk, sk string) []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{{AttributeName: aws.String(pk), KeyType: types.KeyTypeHash}}
+	if sk != "" {
+		schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(sk), KeyType: types.KeyTypeRange})
+	}
+	return schema
+}
+
+// pendingWrite is a validated write, applied once all the writes of a request are validated.
+type pendingWrite struct {
+	table *table
+	id    string
+	// ok Whether the condition of the write holds.
+	ok bool
+	// old The item before the write, if any.
+	old item
+	// new The item after the write. Nil for deletes.
+	new item
+	// check Whether the write only checks a condition.
+	check bool
+}
+
+func (w *pendingWrite) target() string {
+	return w.table.Name + "\x00" + w.id
+}
+
+func (w *pendingWrite) apply() {
+	switch {
+	case w.check:
+	case w.new == nil:
+		delete(w.table.items, w.id)
+	default:
+		w.table.items[w.id] = w.new
+	}
+}
+
+func (c *Client) get(tableName *string, key item, projectionExpr *string, names map[string]string) (item, error) {
+	t, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := t.keyID(key)
+	if err != nil {
+		return nil, err
+	}
+	var projection []path
+	if projectionExpr != nil {
+		p := newPlaceholders(names, nil)
+		if projection, err = parseProjection(*projectionExpr, p); err != nil {
+			return nil, err
+		}
+		if err := p.checkUnused(); err != nil {
+			return nil, err
+		}
+	}
+	it, ok := t.items[id]
+	if !ok {
+		return nil, nil
+	}
+	if projection != nil {
+		return project(it, projection), nil
+	}
+	return copyItem(it), nil
+}
+
+func (c *Client) preparePut(tableName *string, newItem item, conditionExpr *string, names map[string]string, values map[string]types.AttributeValue) (*pendingWrite, error) {
+	t, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := t.keyID(t.keyOf(newItem))
+	if err != nil {
+		return nil, validationError("One or more parameter values were invalid: Missing the key %s in the item", t.PartitionKey)
+	}
+	cond, err := parseConditionOnly(conditionExpr, names, values)
+	if err != nil {
+		return nil, err
+	}
+	old := t.items[id]
+	return &pendingWrite{table: t, id: id, ok: cond == nil || cond.eval(old), old: old, new: copyItem(newItem)}, nil
+}
+
+func (c *Client) prepareUpdate(tableName *string, key item, updateExpr, conditionExpr *string, names map[string]string, values map[string]types.AttributeValue) (*pendingWrite, error) {
+	t, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := t.keyID(key)
+	if err != nil {
+		return nil, err
+	}
+	p := newPlaceholders(names, values)
+	var cond condition
+	if conditionExpr != nil {
+		if cond, err = parseCondition(*conditionExpr, p); err != nil {
+			return nil, err
+		}
+	}
+	var actions []updateAction
+	if updateExpr != nil {
+		if actions, err = parseUpdate(*updateExpr, p); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.checkUnused(); err != nil {
+		return nil, err
+	}
+	for _, a := range actions {
+		if a.path[0].name == t.PartitionKey || a.path[0].name == t.SortKey {
+			return nil, validationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", a.path[0].name)
+		}
+	}
+
+	old, exists := t.items[id]
+	ok := cond == nil || cond.eval(old)
+	if !ok {
+		return &pendingWrite{table: t, id: id, old: old}, nil
+	}
+	// Updates create the item if it does not exist.
+	base := old
+	if !exists {
+		base = copyItem(key)
+	}
+	updated, err := applyUpdate(base, actions)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingWrite{table: t, id: id, ok: true, old: old, new: updated}, nil
+}
+
+func (c *Client) prepareDelete(tableName *string, key item, conditionExpr *string, names map[string]string, values map[string]types.AttributeValue) (*pendingWrite, error) {
+	t, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	id, err := t.keyID(key)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := parseConditionOnly(conditionExpr, names, values)
+	if err != nil {
+		return nil, err
+	}
+	old := t.items[id]
+	return &pendingWrite{table: t, id: id, ok: cond == nil || cond.eval(old), old: old}, nil
+}
+
+func (c *Client) prepareConditionCheck(tableName *string, key item, conditionExpr *string, names map[string]string, values map[string]types.AttributeValue) (*pendingWrite, error) {
+	if conditionExpr == nil {
+		return nil, validationError("ConditionCheck requires a ConditionExpression")
+	}
+	w, err := c.prepareDelete(tableName, key, conditionExpr, names, values)
+	if err != nil {
+		return nil, err
+	}
+	w.check = true
+	return w, nil
+}
+
+// parseConditionOnly parses the condition expression of a request that has no other expression.
+func parseConditionOnly(conditionExpr *string, names map[string]string, values map[string]types.AttributeValue) (condition, error) {
+	p := newPlaceholders(names, values)
+	var cond condition
+	if conditionExpr != nil {
+		var err error
+		if cond, err = parseCondition(*conditionExpr, p); err != nil {
+			return nil, err
+		}
+	}
+	return cond, p.checkUnused()
+}
+
+func (c *Client) table(name *string) (*table, error) {
+	if name == nil {
+		return nil, validationError("TableName is required")
+	}
+	t, ok := c.tables[*name]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("Requested resource not found: Table: " + *name + " not found")}
+	}
+	return t, nil
+}
+
+// keyOf returns the key attributes of an item.
+func (t *table) keyOf(it item) item {
+	return projectAttributes(it, []string{t.PartitionKey, t.SortKey})
+}
+
+// keyID validates a key against the key schema of the table, and identifies the item it refers to.
+func (t *table) keyID(key item) (string, error) {
+	expected := 1
+	if t.SortKey != "" {
+		expected = 2
+	}
+	for attr, typ := range map[string]types.ScalarAttributeType{t.PartitionKey: t.PartitionKeyType, t.SortKey: t.SortKeyType} {
+		if attr == "" {
+			continue
+		}
+		var actual types.ScalarAttributeType
+		switch key[attr].(type) {
+		case *types.AttributeValueMemberS:
+			actual = types.ScalarAttributeTypeS
+		case *types.AttributeValueMemberN:
+			actual = types.ScalarAttributeTypeN
+		case *types.AttributeValueMemberB:
+			actual = types.ScalarAttributeTypeB
+		}
+		if actual == "" || (typ != "" && typ != actual) {
+			return "", validationError("The provided key element does not match the schema")
+		}
+	}
+	if len(key) != expected {
+		return "", validationError("The provided key element does not match the schema")
+	}
+	return dynamorm.Key(key).String(), nil
+}
+
+// sort orders items by partition key, then sort key, then by their key within the table for a stable order.
+func (t *table) sort(items []item, pk, sk string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, attr := range []string{pk, sk} {
+			if attr == "" {
+				continue
+			}
+			if cmp, ok := compare(items[i][attr], items[j][attr]); ok && cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return dynamorm.Key(t.keyOf(items[i])).String() < dynamorm.Key(t.keyOf(items[j])).String()
+	})
+}
+
+// constrainsPartitionKey reports whether a key condition has an equality condition on the partition key.
+func constrainsPartitionKey(cond condition, pk string) bool {
+	switch c := cond.(type) {
+	case andCondition:
+		return constrainsPartitionKey(c.left, pk) || constrainsPartitionKey(c.right, pk)
+	case compareCondition:
+		o, ok := c.left.(pathOperand)
+		return ok && c.op == "=" && len(o.path) == 1 && o.path[0].name == pk
+	}
+	return false
+}
+
+// projectAttributes returns the given top-level attributes of an item.
+func projectAttributes(it item, attributes []string) item {
+	projected := item{}
+	for _, attr := range attributes {
+		if v, ok := it[attr]; ok && attr != "" {
+			projected[attr] = v
+		}
+	}
+	return projected
+}
+
+// changedAttributes returns the attributes of from that differ between old and new.
+func changedAttributes(old, new, from item) item {
+	changed := item{}
+	for k, v := range from {
+		if !equal(old[k], new[k]) {
+			changed[k] = v
+		}
+	}
+	return changed
+}
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	c := make(item, len(it))
+	for k, v := range it {
+		c[k] = v
+	}
+	return c
+}
+
+func validationError(format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, args...), Fault: smithy.FaultClient}
+}
+
+// conditionalCheckFailed is the error of a single-item write whose condition failed, which holds the item if asked to.
+func conditionalCheckFailed(w *pendingWrite, rv types.ReturnValuesOnConditionCheckFailure) error {
+	err := &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	if rv == types.ReturnValuesOnConditionCheckFailureAllOld {
+		err.Item = copyItem(w.old)
+	}
+	return err
+}
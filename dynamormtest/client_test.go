@@ -0,0 +1,220 @@
+package dynamormtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var teamsTable = dynamormtest.Table{
+	Name:         "teams",
+	PartitionKey: "PK",
+	SortKey:      "SK",
+	Indexes: []dynamorm.Index{
+		{Name: "ByRole", PartitionKey: "Role", SortKey: "SK", Projection: types.ProjectionTypeKeysOnly},
+	},
+}
+
+func newTeamsRepository(t *testing.T, client *dynamormtest.Client) dynamorm.Repository[*examples.TaggedModel] {
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithIndex(teamsTable.Indexes[0]).
+		Build()
+	assert.Nil(t, err)
+	return repo
+}
+
+func TestClient_CRUD(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	repo := newTeamsRepository(t, client)
+
+	member := &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}
+	assert.NoError(t, repo.Create(ctx, member))
+	assert.ErrorIs(t, repo.Create(ctx, member), dynamorm.ErrAlreadyExists)
+
+	loaded, err := repo.Get(ctx, member.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", loaded.Role)
+
+	loaded.Role = "member"
+	assert.NoError(t, repo.Update(ctx, loaded))
+	assert.Equal(t, dynamorm.KeyValue("member"), client.Item("teams", member.Key())["Role"])
+
+	assert.NoError(t, repo.Patch(ctx, member.Key(), expression.Set(expression.Name("Role"), expression.Value("owner"))))
+	assert.Equal(t, dynamorm.KeyValue("owner"), client.Item("teams", member.Key())["Role"])
+
+	assert.NoError(t, repo.Delete(ctx, member.Key()))
+	_, err = repo.Get(ctx, member.Key())
+	assert.ErrorIs(t, err, dynamorm.ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, member.Key()), dynamorm.ErrDoesNotExist)
+	assert.ErrorIs(t, repo.Update(ctx, member), dynamorm.ErrDoesNotExist)
+	assert.Empty(t, client.Items("teams"))
+}
+
+func TestClient_Query(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	repo := newTeamsRepository(t, client)
+
+	for _, m := range []*examples.TaggedModel{
+		{Team: "T1", Member: "M3", Role: "member"},
+		{Team: "T1", Member: "M1", Role: "admin"},
+		{Team: "T1", Member: "M2", Role: "member"},
+		{Team: "T2", Member: "M1", Role: "member"},
+	} {
+		assert.NoError(t, repo.Create(ctx, m))
+	}
+
+	// Items are sorted by sort key, and paginated.
+	query := dynamorm.NewQuery("PK", dynamorm.KeyValue("T1")).WithLimit(2)
+	page, err := repo.Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []*examples.TaggedModel{
+		{Team: "T1", Member: "M1", Role: "admin"},
+		{Team: "T1", Member: "M2", Role: "member"},
+	}, page.Items)
+	assert.True(t, page.HasMore())
+
+	cursor, err := page.Cursor()
+	assert.NoError(t, err)
+	page, err = repo.Query(ctx, query.WithCursor(cursor))
+	assert.NoError(t, err)
+	assert.Equal(t, []*examples.TaggedModel{{Team: "T1", Member: "M3", Role: "member"}}, page.Items)
+	assert.False(t, page.HasMore())
+
+	// Sort key conditions and filters.
+	page, err = repo.Query(ctx, dynamorm.NewQuery("PK", dynamorm.KeyValue("T1")).
+		WithSortKey(dynamorm.SortKeyGreaterThan("SK", dynamorm.KeyValue("M1"))).
+		WithFilter(expression.Name("Role").Equal(expression.Value("member"))).
+		Descending())
+	assert.NoError(t, err)
+	assert.Equal(t, []*examples.TaggedModel{
+		{Team: "T1", Member: "M3", Role: "member"},
+		{Team: "T1", Member: "M2", Role: "member"},
+	}, page.Items)
+
+	// Indexes only hold the attributes they project.
+	out, err := client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("teams"),
+		IndexName:                 aws.String("ByRole"),
+		KeyConditionExpression:    aws.String("#r = :r"),
+		ExpressionAttributeNames:  map[string]string{"#r": "Role"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":r": dynamorm.KeyValue("admin")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]types.AttributeValue{
+		{"PK": dynamorm.KeyValue("T1"), "SK": dynamorm.KeyValue("M1"), "Role": dynamorm.KeyValue("admin")},
+	}, out.Items)
+}
+
+func TestClient_Transactions(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(
+		teamsTable,
+		dynamormtest.Table{Name: "documents", PartitionKey: "PK"},
+	)
+	teams := newTeamsRepository(t, client)
+	documents, err := dynamorm.NewBuilder[*examples.DocumentModel]().
+		WithClient(client).
+		WithTableName("documents").
+		Build()
+	assert.Nil(t, err)
+
+	doc := &examples.DocumentModel{ID: "D1", Body: "Hello"}
+	assert.NoError(t, documents.Create(ctx, doc))
+	assert.Equal(t, int64(1), doc.Version)
+
+	// A stale copy of the document conflicts.
+	stale := &examples.DocumentModel{ID: "D1", Body: "Stale"}
+	assert.ErrorIs(t, documents.Update(ctx, stale), dynamorm.ErrVersionConflict)
+
+	// Writes of a unit of work are all or nothing.
+	uow := dynamorm.NewUnitOfWork(client)
+	uowCtx := dynamorm.WithUnitOfWork(ctx, uow)
+	assert.NoError(t, teams.Create(uowCtx, &examples.TaggedModel{Team: "T1", Member: "M1"}))
+	assert.NoError(t, documents.Update(uowCtx, stale))
+	err = uow.Commit(ctx)
+	assert.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+	assert.Empty(t, client.Items("teams"))
+
+	uow = dynamorm.NewUnitOfWork(client)
+	uowCtx = dynamorm.WithUnitOfWork(ctx, uow)
+	assert.NoError(t, teams.Create(uowCtx, &examples.TaggedModel{Team: "T1", Member: "M1"}))
+	assert.NoError(t, documents.Update(uowCtx, doc))
+	assert.NoError(t, uow.Commit(ctx))
+	assert.Len(t, client.Items("teams"), 1)
+	assert.Equal(t, int64(2), doc.Version)
+}
+
+func TestClient_UpdateExpressions(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "things", PartitionKey: "PK"})
+	key := map[string]types.AttributeValue{"PK": dynamorm.KeyValue("A")}
+
+	update := func(expr string, values map[string]types.AttributeValue) error {
+		names := map[string]string{}
+		for _, name := range []string{"Count", "Tags", "Log", "Meta", "City"} {
+			if strings.Contains(expr, "#"+name) {
+				names["#"+name] = name
+			}
+		}
+		_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:                 aws.String("things"),
+			Key:                       key,
+			UpdateExpression:          aws.String(expr),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		})
+		return err
+	}
+
+	assert.NoError(t, update("SET #Count = if_not_exists(#Count, :zero) + :one, #Log = :log, #Meta = :meta ADD #Tags :tags", map[string]types.AttributeValue{
+		":zero": &types.AttributeValueMemberN{Value: "0"},
+		":one":  &types.AttributeValueMemberN{Value: "1"},
+		":log":  &types.AttributeValueMemberL{Value: []types.AttributeValue{dynamorm.KeyValue("created")}},
+		":meta": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"City": dynamorm.KeyValue("Ohio")}},
+		":tags": &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+	}))
+	assert.NoError(t, update("SET #Count = #Count + :one, #Log = list_append(#Log, :log), #Meta.#City = :city DELETE #Tags :tags", map[string]types.AttributeValue{
+		":one":  &types.AttributeValueMemberN{Value: "1"},
+		":log":  &types.AttributeValueMemberL{Value: []types.AttributeValue{dynamorm.KeyValue("updated")}},
+		":city": dynamorm.KeyValue("Indiana"),
+		":tags": &types.AttributeValueMemberSS{Value: []string{"a"}},
+	}))
+
+	assert.Equal(t, map[string]types.AttributeValue{
+		"PK":    dynamorm.KeyValue("A"),
+		"Count": &types.AttributeValueMemberN{Value: "2"},
+		"Log":   &types.AttributeValueMemberL{Value: []types.AttributeValue{dynamorm.KeyValue("created"), dynamorm.KeyValue("updated")}},
+		"Meta":  &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{"City": dynamorm.KeyValue("Indiana")}},
+		"Tags":  &types.AttributeValueMemberSS{Value: []string{"b"}},
+	}, client.Item("things", key))
+
+	assert.NoError(t, update("REMOVE #Meta.#City, #Log[0]", nil))
+	assert.Equal(t, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}, client.Item("things", key)["Meta"])
+	assert.Equal(t, &types.AttributeValueMemberL{Value: []types.AttributeValue{dynamorm.KeyValue("updated")}}, client.Item("things", key)["Log"])
+
+	// Requests are validated the way DynamoDB validates them.
+	var apiErr smithy.APIError
+	err := update("SET #Count = :one", map[string]types.AttributeValue{
+		":one":    &types.AttributeValueMemberN{Value: "1"},
+		":unused": &types.AttributeValueMemberN{Value: "1"},
+	})
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "ValidationException", apiErr.ErrorCode())
+	assert.ErrorAs(t, update("SET #Count = :missing", nil), &apiErr)
+	assert.ErrorAs(t, update("SET #Count = #Count +", map[string]types.AttributeValue{}), &apiErr)
+}
@@ -0,0 +1,691 @@
+package dynamormtest
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// item is the attributes of an item. Items are never modified in place: writes replace them, copying what changed.
+type item = map[string]types.AttributeValue
+
+// pathElem is an element of a document path: either an attribute name or a list index.
+type pathElem struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// path is a document path, e.g. Address.City or Tags[0].
+type path []pathElem
+
+// resolve returns the value at the path, if any.
+func (p path) resolve(it item) (types.AttributeValue, bool) {
+	v, ok := it[p[0].name]
+	for _, elem := range p[1:] {
+		if !ok {
+			return nil, false
+		}
+		switch av := v.(type) {
+		case *types.AttributeValueMemberM:
+			if elem.isIndex {
+				return nil, false
+			}
+			v, ok = av.Value[elem.name]
+		case *types.AttributeValueMemberL:
+			if !elem.isIndex || elem.index >= len(av.Value) {
+				return nil, false
+			}
+			v = av.Value[elem.index]
+		default:
+			return nil, false
+		}
+	}
+	return v, ok
+}
+
+// set returns a copy of the item with the value at the path set. Intermediate maps and lists must exist,
+// unless create is true, in which case they are created as needed.
+func (p path) set(it item, v types.AttributeValue, create bool) (item, bool) {
+	root := &types.AttributeValueMemberM{Value: it}
+	updated, ok := setIn(root, p, v, create)
+	if !ok {
+		return nil, false
+	}
+	return updated.(*types.AttributeValueMemberM).Value, true
+}
+
+func setIn(container types.AttributeValue, p path, v types.AttributeValue, create bool) (types.AttributeValue, bool) {
+	elem := p[0]
+	switch c := container.(type) {
+	case *types.AttributeValueMemberM:
+		if elem.isIndex {
+			return nil, false
+		}
+		m := make(map[string]types.AttributeValue, len(c.Value)+1)
+		for k, v := range c.Value {
+			m[k] = v
+		}
+		if len(p) == 1 {
+			m[elem.name] = v
+			return &types.AttributeValueMemberM{Value: m}, true
+		}
+		child, ok := m[elem.name]
+		if !ok {
+			if !create {
+				return nil, false
+			}
+			child = emptyContainer(p[1])
+		}
+		updated, ok := setIn(child, p[1:], v, create)
+		if !ok {
+			return nil, false
+		}
+		m[elem.name] = updated
+		return &types.AttributeValueMemberM{Value: m}, true
+	case *types.AttributeValueMemberL:
+		if !elem.isIndex {
+			return nil, false
+		}
+		l := slices.Clone(c.Value)
+		index := elem.index
+		if index >= len(l) {
+			// Setting past the end of a list appends to it.
+			l = append(l, nil)
+			index = len(l) - 1
+		}
+		if len(p) == 1 {
+			l[index] = v
+			return &types.AttributeValueMemberL{Value: l}, true
+		}
+		child := l[index]
+		if child == nil {
+			if !create {
+				return nil, false
+			}
+			child = emptyContainer(p[1])
+		}
+		updated, ok := setIn(child, p[1:], v, create)
+		if !ok {
+			return nil, false
+		}
+		l[index] = updated
+		return &types.AttributeValueMemberL{Value: l}, true
+	}
+	return nil, false
+}
+
+func emptyContainer(elem pathElem) types.AttributeValue {
+	if elem.isIndex {
+		return &types.AttributeValueMemberL{}
+	}
+	return &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}}
+}
+
+// remove returns a copy of the item without the value at the path. Removing a missing value is a no-op.
+func (p path) remove(it item) item {
+	updated := removeIn(&types.AttributeValueMemberM{Value: it}, p)
+	return updated.(*types.AttributeValueMemberM).Value
+}
+
+func removeIn(container types.AttributeValue, p path) types.AttributeValue {
+	elem := p[0]
+	switch c := container.(type) {
+	case *types.AttributeValueMemberM:
+		child, ok := c.Value[elem.name]
+		if elem.isIndex || !ok {
+			return c
+		}
+		m := make(map[string]types.AttributeValue, len(c.Value))
+		for k, v := range c.Value {
+			m[k] = v
+		}
+		if len(p) == 1 {
+			delete(m, elem.name)
+		} else {
+			m[elem.name] = removeIn(child, p[1:])
+		}
+		return &types.AttributeValueMemberM{Value: m}
+	case *types.AttributeValueMemberL:
+		if !elem.isIndex || elem.index >= len(c.Value) {
+			return c
+		}
+		l := slices.Clone(c.Value)
+		if len(p) == 1 {
+			l = slices.Delete(l, elem.index, elem.index+1)
+		} else {
+			l[elem.index] = removeIn(l[elem.index], p[1:])
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	}
+	return container
+}
+
+// project returns the attributes of the item at the given paths.
+func project(it item, paths []path) item {
+	projected := item{}
+	for _, p := range paths {
+		if v, ok := p.resolve(it); ok {
+			projected, _ = p.set(projected, v, true)
+		}
+	}
+	return projected
+}
+
+// operand is a value within a condition: a path, a value placeholder or size(path).
+type operand interface {
+	value(it item) (types.AttributeValue, bool)
+}
+
+type pathOperand struct{ path path }
+
+func (o pathOperand) value(it item) (types.AttributeValue, bool) {
+	return o.path.resolve(it)
+}
+
+type valueOperand struct{ v types.AttributeValue }
+
+func (o valueOperand) value(item) (types.AttributeValue, bool) {
+	return o.v, true
+}
+
+type sizeOperand struct{ path path }
+
+func (o sizeOperand) value(it item) (types.AttributeValue, bool) {
+	v, ok := o.path.resolve(it)
+	if !ok {
+		return nil, false
+	}
+	var n int
+	switch av := v.(type) {
+	case *types.AttributeValueMemberS:
+		n = len(av.Value)
+	case *types.AttributeValueMemberB:
+		n = len(av.Value)
+	case *types.AttributeValueMemberSS:
+		n = len(av.Value)
+	case *types.AttributeValueMemberNS:
+		n = len(av.Value)
+	case *types.AttributeValueMemberBS:
+		n = len(av.Value)
+	case *types.AttributeValueMemberL:
+		n = len(av.Value)
+	case *types.AttributeValueMemberM:
+		n = len(av.Value)
+	default:
+		return nil, false
+	}
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}, true
+}
+
+// condition is a parsed condition, filter or key condition expression.
+type condition interface {
+	eval(it item) bool
+}
+
+type andCondition struct{ left, right condition }
+
+func (c andCondition) eval(it item) bool { return c.left.eval(it) && c.right.eval(it) }
+
+type orCondition struct{ left, right condition }
+
+func (c orCondition) eval(it item) bool { return c.left.eval(it) || c.right.eval(it) }
+
+type notCondition struct{ cond condition }
+
+func (c notCondition) eval(it item) bool { return !c.cond.eval(it) }
+
+type compareCondition struct {
+	op          string
+	left, right operand
+}
+
+func (c compareCondition) eval(it item) bool {
+	left, ok := c.left.value(it)
+	if !ok {
+		return false
+	}
+	right, ok := c.right.value(it)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "=":
+		return equal(left, right)
+	case "<>":
+		return !equal(left, right)
+	}
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+type betweenCondition struct{ operand, low, high operand }
+
+func (c betweenCondition) eval(it item) bool {
+	v, ok1 := c.operand.value(it)
+	low, ok2 := c.low.value(it)
+	high, ok3 := c.high.value(it)
+	if !ok1 || !ok2 || !ok3 {
+		return false
+	}
+	cmpLow, ok1 := compare(v, low)
+	cmpHigh, ok2 := compare(v, high)
+	return ok1 && ok2 && cmpLow >= 0 && cmpHigh <= 0
+}
+
+type inCondition struct {
+	operand operand
+	list    []operand
+}
+
+func (c inCondition) eval(it item) bool {
+	v, ok := c.operand.value(it)
+	if !ok {
+		return false
+	}
+	for _, o := range c.list {
+		if candidate, ok := o.value(it); ok && equal(v, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+type functionCondition struct {
+	name string
+	path path
+	arg  operand
+}
+
+func (c functionCondition) eval(it item) bool {
+	v, exists := c.path.resolve(it)
+	switch c.name {
+	case "attribute_exists":
+		return exists
+	case "attribute_not_exists":
+		return !exists
+	}
+	if !exists {
+		return false
+	}
+	arg, ok := c.arg.value(it)
+	if !ok {
+		return false
+	}
+	switch c.name {
+	case "attribute_type":
+		t, ok := arg.(*types.AttributeValueMemberS)
+		return ok && typeOf(v) == t.Value
+	case "begins_with":
+		switch av := v.(type) {
+		case *types.AttributeValueMemberS:
+			prefix, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(av.Value, prefix.Value)
+		case *types.AttributeValueMemberB:
+			prefix, ok := arg.(*types.AttributeValueMemberB)
+			return ok && bytes.HasPrefix(av.Value, prefix.Value)
+		}
+	case "contains":
+		switch av := v.(type) {
+		case *types.AttributeValueMemberS:
+			sub, ok := arg.(*types.AttributeValueMemberS)
+			return ok && strings.Contains(av.Value, sub.Value)
+		case *types.AttributeValueMemberB:
+			sub, ok := arg.(*types.AttributeValueMemberB)
+			return ok && bytes.Contains(av.Value, sub.Value)
+		case *types.AttributeValueMemberL:
+			return slices.ContainsFunc(av.Value, func(e types.AttributeValue) bool { return equal(e, arg) })
+		case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+			return slices.ContainsFunc(setElements(av), func(e types.AttributeValue) bool { return equal(e, arg) })
+		}
+	}
+	return false
+}
+
+// typeOf returns the type descriptor of a value, e.g. "S" or "NS".
+func typeOf(v types.AttributeValue) string {
+	switch v.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	}
+	return ""
+}
+
+// setElements returns the elements of a set as individual values.
+func setElements(v types.AttributeValue) []types.AttributeValue {
+	var elems []types.AttributeValue
+	switch av := v.(type) {
+	case *types.AttributeValueMemberSS:
+		for _, s := range av.Value {
+			elems = append(elems, &types.AttributeValueMemberS{Value: s})
+		}
+	case *types.AttributeValueMemberNS:
+		for _, n := range av.Value {
+			elems = append(elems, &types.AttributeValueMemberN{Value: n})
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range av.Value {
+			elems = append(elems, &types.AttributeValueMemberB{Value: b})
+		}
+	}
+	return elems
+}
+
+// parseNumber parses the value of a number attribute.
+func parseNumber(s string) (*big.Float, bool) {
+	f, ok := new(big.Float).SetPrec(256).SetString(s)
+	return f, ok
+}
+
+// formatNumber formats a number the way DynamoDB returns them.
+func formatNumber(f *big.Float) string {
+	return f.Text('f', -1)
+}
+
+// compare orders two scalar values of the same type. Numbers are compared numerically, strings and binaries
+// bytewise. Reports false if the values cannot be compared.
+func compare(a, b types.AttributeValue) (int, bool) {
+	switch av := a.(type) {
+	case *types.AttributeValueMemberS:
+		bv, ok := b.(*types.AttributeValueMemberS)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av.Value, bv.Value), true
+	case *types.AttributeValueMemberN:
+		bv, ok := b.(*types.AttributeValueMemberN)
+		if !ok {
+			return 0, false
+		}
+		x, ok1 := parseNumber(av.Value)
+		y, ok2 := parseNumber(bv.Value)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case *types.AttributeValueMemberB:
+		bv, ok := b.(*types.AttributeValueMemberB)
+		if !ok {
+			return 0, false
+		}
+		return bytes.Compare(av.Value, bv.Value), true
+	}
+	return 0, false
+}
+
+// equal reports whether two values are equal. Numbers are compared numerically and sets regardless of order.
+func equal(a, b types.AttributeValue) bool {
+	if typeOf(a) != typeOf(b) {
+		return false
+	}
+	switch av := a.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		cmp, ok := compare(a, b)
+		return ok && cmp == 0
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		as, bs := setElements(a), setElements(b)
+		if len(as) != len(bs) {
+			return false
+		}
+		for _, e := range as {
+			if !slices.ContainsFunc(bs, func(f types.AttributeValue) bool { return equal(e, f) }) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberL:
+		bv := b.(*types.AttributeValueMemberL)
+		return slices.EqualFunc(av.Value, bv.Value, equal)
+	case *types.AttributeValueMemberM:
+		bv := b.(*types.AttributeValueMemberM)
+		if len(av.Value) != len(bv.Value) {
+			return false
+		}
+		for k, v := range av.Value {
+			w, ok := bv.Value[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case *types.AttributeValueMemberBOOL:
+		return av.Value == b.(*types.AttributeValueMemberBOOL).Value
+	case *types.AttributeValueMemberNULL:
+		return true
+	}
+	return false
+}
+
+// setValue is the value of a SET action of an update expression.
+type setValue interface {
+	eval(it item) (types.AttributeValue, error)
+}
+
+type operandValue struct{ operand operand }
+
+func (v operandValue) eval(it item) (types.AttributeValue, error) {
+	value, ok := v.operand.value(it)
+	if !ok {
+		return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+	}
+	return value, nil
+}
+
+type arithmeticValue struct {
+	op          string
+	left, right setValue
+}
+
+func (v arithmeticValue) eval(it item) (types.AttributeValue, error) {
+	left, err := v.left.eval(it)
+	if err != nil {
+		return nil, err
+	}
+	right, err := v.right.eval(it)
+	if err != nil {
+		return nil, err
+	}
+	ln, ok1 := left.(*types.AttributeValueMemberN)
+	rn, ok2 := right.(*types.AttributeValueMemberN)
+	if !ok1 || !ok2 {
+		return nil, validationError("An operand in the update expression has an incorrect data type")
+	}
+	x, ok1 := parseNumber(ln.Value)
+	y, ok2 := parseNumber(rn.Value)
+	if !ok1 || !ok2 {
+		return nil, validationError("An operand in the update expression is not a valid number")
+	}
+	if v.op == "+" {
+		x.Add(x, y)
+	} else {
+		x.Sub(x, y)
+	}
+	return &types.AttributeValueMemberN{Value: formatNumber(x)}, nil
+}
+
+type ifNotExistsValue struct {
+	path     path
+	fallback setValue
+}
+
+func (v ifNotExistsValue) eval(it item) (types.AttributeValue, error) {
+	if value, ok := v.path.resolve(it); ok {
+		return value, nil
+	}
+	return v.fallback.eval(it)
+}
+
+type listAppendValue struct{ left, right setValue }
+
+func (v listAppendValue) eval(it item) (types.AttributeValue, error) {
+	left, err := v.left.eval(it)
+	if err != nil {
+		return nil, err
+	}
+	right, err := v.right.eval(it)
+	if err != nil {
+		return nil, err
+	}
+	ll, ok1 := left.(*types.AttributeValueMemberL)
+	rl, ok2 := right.(*types.AttributeValueMemberL)
+	if !ok1 || !ok2 {
+		return nil, validationError("An operand in the update expression has an incorrect data type")
+	}
+	return &types.AttributeValueMemberL{Value: append(slices.Clone(ll.Value), rl.Value...)}, nil
+}
+
+// applyUpdate returns a copy of the item with the actions of an update expression applied.
+// All values are computed from the item as it was before the update, as DynamoDB does.
+func applyUpdate(it item, actions []updateAction) (item, error) {
+	values := make([]types.AttributeValue, len(actions))
+	for i, a := range actions {
+		var err error
+		switch a.verb {
+		case "SET":
+			values[i], err = a.set.eval(it)
+		case "ADD", "DELETE":
+			values[i], err = operandValue{a.operand}.eval(it)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updated := it
+	for i, a := range actions {
+		var ok bool
+		switch a.verb {
+		case "SET":
+			updated, ok = a.path.set(updated, values[i], false)
+			if !ok {
+				return nil, validationError("The document path provided in the update expression is invalid for update")
+			}
+		case "REMOVE":
+			updated = a.path.remove(updated)
+		case "ADD":
+			current, exists := a.path.resolve(updated)
+			value, err := add(current, exists, values[i])
+			if err != nil {
+				return nil, err
+			}
+			if updated, ok = a.path.set(updated, value, false); !ok {
+				return nil, validationError("The document path provided in the update expression is invalid for update")
+			}
+		case "DELETE":
+			current, exists := a.path.resolve(updated)
+			if !exists {
+				continue
+			}
+			value, err := subtract(current, values[i])
+			if err != nil {
+				return nil, err
+			}
+			if value == nil {
+				updated = a.path.remove(updated)
+			} else {
+				updated, _ = a.path.set(updated, value, false)
+			}
+		}
+	}
+	return updated, nil
+}
+
+// add implements the ADD action: adds to a number, or to a set.
+func add(current types.AttributeValue, exists bool, value types.AttributeValue) (types.AttributeValue, error) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberN:
+		if !exists {
+			return v, nil
+		}
+		return arithmeticValue{op: "+", left: operandValue{valueOperand{current}}, right: operandValue{valueOperand{v}}}.eval(nil)
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		if !exists {
+			return v, nil
+		}
+		if typeOf(current) != typeOf(v) {
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+		elems := setElements(current)
+		for _, e := range setElements(v) {
+			if !slices.ContainsFunc(elems, func(f types.AttributeValue) bool { return equal(e, f) }) {
+				elems = append(elems, e)
+			}
+		}
+		return newSet(typeOf(v), elems), nil
+	}
+	return nil, validationError("An operand in the update expression has an incorrect data type")
+}
+
+// subtract implements the DELETE action: removes elements from a set. Returns nil if the set ends up empty.
+func subtract(current, value types.AttributeValue) (types.AttributeValue, error) {
+	if typeOf(current) != typeOf(value) || len(setElements(value)) == 0 {
+		return nil, validationError("An operand in the update expression has an incorrect data type")
+	}
+	var elems []types.AttributeValue
+	for _, e := range setElements(current) {
+		if !slices.ContainsFunc(setElements(value), func(f types.AttributeValue) bool { return equal(e, f) }) {
+			elems = append(elems, e)
+		}
+	}
+	if len(elems) == 0 {
+		return nil, nil
+	}
+	return newSet(typeOf(current), elems), nil
+}
+
+func newSet(setType string, elems []types.AttributeValue) types.AttributeValue {
+	switch setType {
+	case "SS":
+		set := &types.AttributeValueMemberSS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberS).Value)
+		}
+		return set
+	case "NS":
+		set := &types.AttributeValueMemberNS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberN).Value)
+		}
+		return set
+	default:
+		set := &types.AttributeValueMemberBS{}
+		for _, e := range elems {
+			set.Value = append(set.Value, e.(*types.AttributeValueMemberB).Value)
+		}
+		return set
+	}
+}
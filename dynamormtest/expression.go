@@ -0,0 +1,542 @@
+package dynamormtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// This file parses the expressions of DynamoDB requests: condition, filter and key condition expressions,
+// update expressions and projection expressions. See
+// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/Expressions.html for their grammar.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenIdent A function name, keyword or attribute name, e.g. attribute_exists, AND or Name.
+	tokenIdent
+	// tokenName An expression attribute name placeholder, e.g. #0.
+	tokenName
+	// tokenValue An expression attribute value placeholder, e.g. :0.
+	tokenValue
+	// tokenNumber A list index, e.g. the 1 of #0[1].
+	tokenNumber
+	// tokenPunct An operator or punctuation, e.g. <= or (.
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an expression into tokens.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i]})
+		case c == '#' || c == ':' || isIdentRune(c):
+			start := i
+			i++
+			for i < len(expr) && isIdentRune(rune(expr[i])) {
+				i++
+			}
+			kind := tokenIdent
+			if c == '#' {
+				kind = tokenName
+			} else if c == ':' {
+				kind = tokenValue
+			}
+			if i-start == 1 && kind != tokenIdent {
+				return nil, fmt.Errorf("invalid expression %q: empty placeholder at %d", expr, start)
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[start:i]})
+		case strings.HasPrefix(expr[i:], "<>") || strings.HasPrefix(expr[i:], "<=") || strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, token{kind: tokenPunct, text: expr[i : i+2]})
+			i += 2
+		case strings.ContainsRune("(),.[]=<>+-", c):
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("invalid expression %q: unexpected %q at %d", expr, c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// placeholders resolves the placeholders of the expressions of a request, and tracks which ones were used:
+// DynamoDB rejects requests with placeholders that no expression uses.
+type placeholders struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newPlaceholders(names map[string]string, values map[string]types.AttributeValue) *placeholders {
+	return &placeholders{
+		names:      names,
+		values:     values,
+		usedNames:  map[string]bool{},
+		usedValues: map[string]bool{},
+	}
+}
+
+// checkUnused fails if some placeholders were not used by any expression.
+func (p *placeholders) checkUnused() error {
+	for name := range p.names {
+		if !p.usedNames[name] {
+			return validationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", name)
+		}
+	}
+	for value := range p.values {
+		if !p.usedValues[value] {
+			return validationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", value)
+		}
+	}
+	return nil
+}
+
+// parser is a recursive descent parser of a single expression.
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+	p      *placeholders
+}
+
+func newParser(expr string, p *placeholders) (*parser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, validationError("%v", err)
+	}
+	return &parser{expr: expr, tokens: tokens, p: p}, nil
+}
+
+func (ps *parser) peek() token {
+	return ps.tokens[ps.pos]
+}
+
+func (ps *parser) next() token {
+	t := ps.tokens[ps.pos]
+	if t.kind != tokenEOF {
+		ps.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, case-insensitively, and consumes it if so.
+func (ps *parser) keyword(word string) bool {
+	if t := ps.peek(); t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		ps.pos++
+		return true
+	}
+	return false
+}
+
+// punct reports whether the next token is the given punctuation, and consumes it if so.
+func (ps *parser) punct(p string) bool {
+	if t := ps.peek(); t.kind == tokenPunct && t.text == p {
+		ps.pos++
+		return true
+	}
+	return false
+}
+
+func (ps *parser) expect(p string) error {
+	if !ps.punct(p) {
+		return ps.errorf("expected %q", p)
+	}
+	return nil
+}
+
+func (ps *parser) errorf(format string, args ...interface{}) error {
+	near := ps.peek().text
+	if ps.peek().kind == tokenEOF {
+		near = "<EOF>"
+	}
+	return validationError("Invalid expression %q: %s near %q", ps.expr, fmt.Sprintf(format, args...), near)
+}
+
+func (ps *parser) end() error {
+	if ps.peek().kind != tokenEOF {
+		return ps.errorf("unexpected token")
+	}
+	return nil
+}
+
+// parseCondition parses a condition, filter or key condition expression.
+func parseCondition(expr string, p *placeholders) (condition, error) {
+	ps, err := newParser(expr, p)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := ps.or()
+	if err != nil {
+		return nil, err
+	}
+	return cond, ps.end()
+}
+
+func (ps *parser) or() (condition, error) {
+	left, err := ps.and()
+	if err != nil {
+		return nil, err
+	}
+	for ps.keyword("OR") {
+		right, err := ps.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left, right}
+	}
+	return left, nil
+}
+
+func (ps *parser) and() (condition, error) {
+	left, err := ps.not()
+	if err != nil {
+		return nil, err
+	}
+	for ps.keyword("AND") {
+		right, err := ps.not()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left, right}
+	}
+	return left, nil
+}
+
+func (ps *parser) not() (condition, error) {
+	if ps.keyword("NOT") {
+		cond, err := ps.not()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{cond}, nil
+	}
+	return ps.primary()
+}
+
+func (ps *parser) primary() (condition, error) {
+	if ps.punct("(") {
+		cond, err := ps.or()
+		if err != nil {
+			return nil, err
+		}
+		return cond, ps.expect(")")
+	}
+
+	// Functions, other than size, are conditions of their own.
+	if t := ps.peek(); t.kind == tokenIdent && ps.tokens[ps.pos+1].text == "(" {
+		name := strings.ToLower(t.text)
+		switch name {
+		case "attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains":
+			ps.next()
+			ps.next()
+			path, err := ps.path()
+			if err != nil {
+				return nil, err
+			}
+			cond := functionCondition{name: name, path: path}
+			if name != "attribute_exists" && name != "attribute_not_exists" {
+				if err := ps.expect(","); err != nil {
+					return nil, err
+				}
+				if cond.arg, err = ps.operand(); err != nil {
+					return nil, err
+				}
+			}
+			return cond, ps.expect(")")
+		}
+	}
+
+	left, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ps.keyword("BETWEEN"):
+		low, err := ps.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !ps.keyword("AND") {
+			return nil, ps.errorf("expected AND")
+		}
+		high, err := ps.operand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{left, low, high}, nil
+	case ps.keyword("IN"):
+		if err := ps.expect("("); err != nil {
+			return nil, err
+		}
+		cond := inCondition{operand: left}
+		for {
+			o, err := ps.operand()
+			if err != nil {
+				return nil, err
+			}
+			cond.list = append(cond.list, o)
+			if !ps.punct(",") {
+				break
+			}
+		}
+		return cond, ps.expect(")")
+	}
+	t := ps.next()
+	switch t.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+		if t.kind != tokenPunct {
+			break
+		}
+		right, err := ps.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compareCondition{op: t.text, left: left, right: right}, nil
+	}
+	ps.pos--
+	return nil, ps.errorf("expected a comparator")
+}
+
+// operand parses a path, a value placeholder or size(path).
+func (ps *parser) operand() (operand, error) {
+	t := ps.peek()
+	switch {
+	case t.kind == tokenValue:
+		ps.next()
+		v, ok := ps.p.values[t.text]
+		if !ok {
+			return nil, validationError("An expression attribute value used in expression is not defined; attribute value: %s", t.text)
+		}
+		ps.p.usedValues[t.text] = true
+		return valueOperand{v}, nil
+	case t.kind == tokenIdent && strings.EqualFold(t.text, "size") && ps.tokens[ps.pos+1].text == "(":
+		ps.next()
+		ps.next()
+		path, err := ps.path()
+		if err != nil {
+			return nil, err
+		}
+		return sizeOperand{path}, ps.expect(")")
+	}
+	path, err := ps.path()
+	if err != nil {
+		return nil, err
+	}
+	return pathOperand{path}, nil
+}
+
+// path parses a document path, e.g. #0, #0.#1 or #0[2].
+func (ps *parser) path() (path, error) {
+	var p path
+	elem, err := ps.pathName()
+	if err != nil {
+		return nil, err
+	}
+	p = append(p, pathElem{name: elem})
+	for {
+		switch {
+		case ps.punct("."):
+			elem, err := ps.pathName()
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, pathElem{name: elem})
+		case ps.punct("["):
+			t := ps.next()
+			if t.kind != tokenNumber {
+				return nil, ps.errorf("expected a list index")
+			}
+			index, err := strconv.Atoi(t.text)
+			if err != nil {
+				return nil, ps.errorf("invalid list index")
+			}
+			p = append(p, pathElem{index: index, isIndex: true})
+			if err := ps.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return p, nil
+		}
+	}
+}
+
+func (ps *parser) pathName() (string, error) {
+	t := ps.next()
+	switch t.kind {
+	case tokenName:
+		name, ok := ps.p.names[t.text]
+		if !ok {
+			return "", validationError("An expression attribute name used in the document path is not defined; attribute name: %s", t.text)
+		}
+		ps.p.usedNames[t.text] = true
+		return name, nil
+	case tokenIdent:
+		return t.text, nil
+	}
+	ps.pos--
+	return "", ps.errorf("expected an attribute name")
+}
+
+// updateAction is a single action of an update expression.
+type updateAction struct {
+	// verb SET, REMOVE, ADD or DELETE.
+	verb string
+	path path
+	// set The value of SET actions.
+	set setValue
+	// operand The operand of ADD and DELETE actions.
+	operand operand
+}
+
+// parseUpdate parses an update expression.
+func parseUpdate(expr string, p *placeholders) ([]updateAction, error) {
+	ps, err := newParser(expr, p)
+	if err != nil {
+		return nil, err
+	}
+	var actions []updateAction
+	seen := map[string]bool{}
+	for ps.peek().kind != tokenEOF {
+		t := ps.next()
+		verb := strings.ToUpper(t.text)
+		if t.kind != tokenIdent || (verb != "SET" && verb != "REMOVE" && verb != "ADD" && verb != "DELETE") {
+			ps.pos--
+			return nil, ps.errorf("expected SET, REMOVE, ADD or DELETE")
+		}
+		if seen[verb] {
+			return nil, ps.errorf("the %s section can only be used once", verb)
+		}
+		seen[verb] = true
+		for {
+			path, err := ps.path()
+			if err != nil {
+				return nil, err
+			}
+			action := updateAction{verb: verb, path: path}
+			switch verb {
+			case "SET":
+				if err := ps.expect("="); err != nil {
+					return nil, err
+				}
+				if action.set, err = ps.setValue(); err != nil {
+					return nil, err
+				}
+			case "ADD", "DELETE":
+				if action.operand, err = ps.operand(); err != nil {
+					return nil, err
+				}
+			}
+			actions = append(actions, action)
+			if !ps.punct(",") {
+				break
+			}
+		}
+	}
+	if len(actions) == 0 {
+		return nil, ps.errorf("empty update expression")
+	}
+	return actions, nil
+}
+
+// setValue parses the value of a SET action: an operand, or the sum or difference of two operands.
+func (ps *parser) setValue() (setValue, error) {
+	left, err := ps.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"+", "-"} {
+		if ps.punct(op) {
+			right, err := ps.setOperand()
+			if err != nil {
+				return nil, err
+			}
+			return arithmeticValue{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (ps *parser) setOperand() (setValue, error) {
+	if t := ps.peek(); t.kind == tokenIdent && ps.tokens[ps.pos+1].text == "(" {
+		switch strings.ToLower(t.text) {
+		case "if_not_exists":
+			ps.next()
+			ps.next()
+			path, err := ps.path()
+			if err != nil {
+				return nil, err
+			}
+			if err := ps.expect(","); err != nil {
+				return nil, err
+			}
+			fallback, err := ps.setValue()
+			if err != nil {
+				return nil, err
+			}
+			return ifNotExistsValue{path: path, fallback: fallback}, ps.expect(")")
+		case "list_append":
+			ps.next()
+			ps.next()
+			left, err := ps.setValue()
+			if err != nil {
+				return nil, err
+			}
+			if err := ps.expect(","); err != nil {
+				return nil, err
+			}
+			right, err := ps.setValue()
+			if err != nil {
+				return nil, err
+			}
+			return listAppendValue{left: left, right: right}, ps.expect(")")
+		}
+	}
+	o, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	return operandValue{o}, nil
+}
+
+// parseProjection parses a projection expression: a comma-separated list of paths.
+func parseProjection(expr string, p *placeholders) ([]path, error) {
+	ps, err := newParser(expr, p)
+	if err != nil {
+		return nil, err
+	}
+	var paths []path
+	for {
+		path, err := ps.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !ps.punct(",") {
+			break
+		}
+	}
+	return paths, ps.end()
+}
@@ -1,7 +1,147 @@
 package dynamorm
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var ErrNotFound = errors.New("not found")
 
+// IncompatibleModelerError is returned by modelers for items that they do not support, e.g. by a PolymorphicModeler
+// for items of an unknown kind. Reads fail with it, unless Builder.WithUnmatchedItems says otherwise.
 var IncompatibleModelerError = errors.New("modeler does not support this item")
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var ErrUnknownIndex = errors.New("unknown index")
+
+var ErrNotTracked = errors.New("model is not tracked")
+
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrAlreadyExists is returned when Create fails because the item already exists.
+var ErrAlreadyExists = errors.New("item already exists")
+
+// ErrDoesNotExist is returned when Update, Patch or Delete fail because the item does not exist.
+var ErrDoesNotExist = errors.New("item does not exist")
+
+// ErrConditionFailed is returned when the condition expression of a related model fails.
+var ErrConditionFailed = errors.New("condition failed")
+
+// ErrUnitOfWorkCommitted is returned when writing to or committing a UnitOfWork that was already committed.
+var ErrUnitOfWorkCommitted = errors.New("unit of work already committed")
+
+// ErrInvalidConfiguration is returned by Builder.Build when the configuration of the repository is incomplete
+// or invalid. The error describes every problem that was found.
+var ErrInvalidConfiguration = errors.New("invalid repository configuration")
+
+// ErrTableNotFound is returned by Builder.BuildAndVerify when the table does not exist.
+var ErrTableNotFound = errors.New("table not found")
+
+// ErrSchemaMismatch is returned by Builder.BuildAndVerify when the table does not match the configuration
+// of the repository, e.g. its key schema differs from the model's key.
+var ErrSchemaMismatch = errors.New("table schema mismatch")
+
+// ErrInvalidKey is returned when a key does not conform to the key schema set with Builder.WithKeySchema.
+// The error describes every problem with the key; no request is sent.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ErrIdempotentParameterMismatch is returned when a write is sent with an idempotency token that was already used
+// for a different request. The *types.IdempotentParameterMismatchException remains in the error chain.
+var ErrIdempotentParameterMismatch = errors.New("idempotency token reused for a different request")
+
+// ErrPartialModel is returned when writing a model that was read with the Project option in full, which would
+// overwrite the attributes that were not read.
+var ErrPartialModel = errors.New("model was only partially read")
+
+// TransactionError is returned when a transaction is canceled.
+// It maps each cancellation reason back to the model whose item caused it.
+//
+// The errors of the failures are part of the error chain, so that errors.Is(err, ErrAlreadyExists) holds
+// if the primary item of a Create already existed, even when it was saved along with related models.
+type TransactionError struct {
+	// Failures The items that caused the cancellation, in transaction order.
+	Failures []TransactionFailure
+	// Err The underlying error, which wraps a *types.TransactionCanceledException.
+	Err error
+}
+
+// TransactionFailure is an item that caused a transaction to be canceled.
+type TransactionFailure struct {
+	// Index The position of the item within the transaction.
+	Index int
+	// Model The model whose item caused the failure. Nil for items written by key.
+	Model Model
+	// Primary Whether the model is the one the operation was called for, as opposed to one of its related models.
+	Primary bool
+	// Code The cancellation reason code, e.g. "ConditionalCheckFailed" or "TransactionConflict".
+	Code string
+	// Message The cancellation reason message.
+	Message string
+	// Err The error the failure maps to, e.g. ErrAlreadyExists. Nil if the code is not "ConditionalCheckFailed".
+	Err error
+}
+
+func (e *TransactionError) Error() string {
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("transaction canceled: %v", e.Err)
+	}
+	reasons := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		reason := fmt.Sprintf("item %d (%T): %s", f.Index, f.Model, f.Code)
+		if f.Err != nil {
+			reason += ": " + f.Err.Error()
+		}
+		reasons = append(reasons, reason)
+	}
+	return "transaction canceled: " + strings.Join(reasons, "; ")
+}
+
+func (e *TransactionError) Unwrap() []error {
+	errs := []error{e.Err}
+	for _, f := range e.Failures {
+		if f.Err != nil {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errs
+}
+
+// RelatedError is returned when the related models of a model cannot be written, e.g. because Related() failed or
+// a related model could not be marshalled. Nothing is written when this happens.
+type RelatedError struct {
+	// Parent The model whose related models failed.
+	Parent Model
+	// Related The related model that failed. Nil if Related() itself failed.
+	Related Model
+	// Index The position of the related model within the result of Related(). -1 if Related() itself failed.
+	Index int
+	// Err The underlying error.
+	Err error
+}
+
+func (e *RelatedError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("related models of %T: %v", e.Parent, e.Err)
+	}
+	return fmt.Sprintf("related model %d (%T) of %T: %v", e.Index, e.Related, e.Parent, e.Err)
+}
+
+func (e *RelatedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrRelatedCycle is returned when a related model has the same table and key as one of the models it is related from.
+var ErrRelatedCycle = errors.New("related models form a cycle")
+
+// ErrRelatedTooDeep is returned when related models are nested deeper than the configured maximum depth.
+var ErrRelatedTooDeep = errors.New("related models are nested too deep")
+
+// ErrTransactionTooLarge is returned when a write exceeds the item count or size limits of a single transaction,
+// and the repository was not built with Builder.WithChunkedTransactions.
+var ErrTransactionTooLarge = errors.New("transaction is too large")
+
+// ErrUnprocessed is returned when DynamoDB keeps leaving keys or items of a batch operation unprocessed,
+// even after retrying them.
+var ErrUnprocessed = errors.New("unprocessed after retries")
@@ -4,15 +4,16 @@ go 1.22.3
 
 require (
 	github.com/aws/aws-sdk-go v1.53.3
+	github.com/aws/aws-sdk-go-v2 v1.26.2
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.13.16
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression v1.7.16
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.32.2
+	github.com/aws/smithy-go v1.20.2
 	github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools v0.0.0-20240515184554-f5a74bb68b09
 	github.com/stretchr/testify v1.9.0
 )
 
 require (
-	github.com/aws/aws-sdk-go-v2 v1.26.2 // indirect
 	github.com/aws/aws-sdk-go-v2/config v1.15.3 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.11.2 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.12.3 // indirect
@@ -25,7 +26,6 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.9.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sso v1.11.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.16.3 // indirect
-	github.com/aws/smithy-go v1.20.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -0,0 +1,98 @@
+package dynamorm
+
+import "context"
+
+// BeforeCreate is an optional interface that models can implement to be called before they are written by
+// Repository.Create, e.g. to set timestamps. Related models written along with the model are called too.
+// Returning an error aborts the operation: nothing is written.
+type BeforeCreate interface {
+	BeforeCreate(ctx context.Context) error
+}
+
+// BeforeUpdate is an optional interface that models can implement to be called before they are written by
+// Repository.Update or Repository.PatchModel. Related models written along with the model are called too.
+// Returning an error aborts the operation: nothing is written.
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// BeforeSave is an optional interface that models can implement to be called before they are written by
+// Repository.Save. Related models written along with the model are called too.
+// Returning an error aborts the operation: nothing is written.
+type BeforeSave interface {
+	BeforeSave(ctx context.Context) error
+}
+
+// BeforeDelete is an optional interface that models can implement to be called before they are deleted by
+// Repository.DeleteModel. Related models deleted along with the model are called too.
+// Returning an error aborts the operation: nothing is deleted.
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// AfterSave is an optional interface that models can implement to be called once they were written by
+// Repository.Create, Repository.Update, Repository.PatchModel or Repository.Save, along with the related models that were written
+// with them. It is only called when the write succeeded, e.g. to reset state that tracks what was persisted.
+// Within a UnitOfWork, it is called when the unit of work is committed.
+type AfterSave interface {
+	AfterSave(ctx context.Context)
+}
+
+// AfterLoad is an optional interface that models can implement to be called once they were read and modeled by
+// Repository.Get, Repository.BatchGet or Repository.Query. Returning an error fails the read.
+type AfterLoad interface {
+	AfterLoad(ctx context.Context) error
+}
+
+// operation is a kind of write that models are called before.
+type operation int
+
+const (
+	operationCreate operation = iota
+	operationUpdate
+	operationSave
+	operationDelete
+)
+
+// before calls the hook of the model for the operation, if it implements it.
+func before(ctx context.Context, op operation, model Model) error {
+	switch op {
+	case operationCreate:
+		if m, ok := model.(BeforeCreate); ok {
+			return m.BeforeCreate(ctx)
+		}
+	case operationUpdate:
+		if m, ok := model.(BeforeUpdate); ok {
+			return m.BeforeUpdate(ctx)
+		}
+	case operationSave:
+		if m, ok := model.(BeforeSave); ok {
+			return m.BeforeSave(ctx)
+		}
+	case operationDelete:
+		if m, ok := model.(BeforeDelete); ok {
+			return m.BeforeDelete(ctx)
+		}
+	}
+	return nil
+}
+
+// afterSave calls the AfterSave hook of the models that were written, if they implement it. Deletes are skipped.
+func afterSave(ctx context.Context, writes []write) {
+	for _, w := range writes {
+		if w.model == nil || w.item.Delete != nil {
+			continue
+		}
+		if m, ok := w.model.(AfterSave); ok {
+			m.AfterSave(ctx)
+		}
+	}
+}
+
+// afterLoad calls the AfterLoad hook of the model, if it implements it.
+func afterLoad(ctx context.Context, model Model) error {
+	if m, ok := model.(AfterLoad); ok {
+		return m.AfterLoad(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package dynamorm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaxIdempotencyTokenLength is the maximum length of a ClientRequestToken.
+const MaxIdempotencyTokenLength = 36
+
+type idempotencyTokenKey struct{}
+
+// WithIdempotencyToken returns a context whose writes are made idempotent with the token.
+//
+// Writes made with the context are sent with TransactWriteItems, even single-item ones, passing the token as its
+// ClientRequestToken: should the request be retried with the same token, e.g. by a retried Lambda invocation,
+// DynamoDB does not apply it twice. Tokens are valid for 10 minutes. Reusing a token for a different request fails
+// with ErrIdempotentParameterMismatch, so each write operation needs its own token.
+func WithIdempotencyToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, idempotencyTokenKey{}, token)
+}
+
+// IdempotencyTokenFrom returns the idempotency token bound to the context, if any.
+func IdempotencyTokenFrom(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(idempotencyTokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// idempotencyToken returns the token that writes made with the context are sent with: the one bound to the context
+// if any, otherwise the one derived from the context by the repository's token provider, if any.
+func (r *repositoryImpl[T]) idempotencyToken(ctx context.Context) (string, error) {
+	token, ok := IdempotencyTokenFrom(ctx)
+	if !ok && r.tokenProvider != nil {
+		token = r.tokenProvider(ctx)
+	}
+	if len(token) > MaxIdempotencyTokenLength {
+		return "", fmt.Errorf("idempotency token is longer than %d characters", MaxIdempotencyTokenLength)
+	}
+	return token, nil
+}
+
+// deriveToken derives a token from another, for a request that is part of the same operation, e.g. a batch of a
+// chunked transaction. The same token and purpose always derive the same token.
+func deriveToken(token, purpose string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token + "/" + purpose))
+	return hex.EncodeToString(sum[:])[:MaxIdempotencyTokenLength]
+}
+
+// idempotencyError maps an IdempotentParameterMismatchException to ErrIdempotentParameterMismatch.
+// The original error remains in the chain.
+func idempotencyError(err error) error {
+	var mismatch *types.IdempotentParameterMismatchException
+	if errors.As(err, &mismatch) {
+		return fmt.Errorf("%w: %w", ErrIdempotentParameterMismatch, err)
+	}
+	return err
+}
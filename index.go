@@ -0,0 +1,49 @@
+package dynamorm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Index describes a global or local secondary index of the table, so that it can be queried through the repository.
+type Index struct {
+	// Name The name of the index.
+	Name string
+	// PartitionKey The name of the partition key attribute of the index.
+	PartitionKey string
+	// SortKey The name of the sort key attribute of the index, if any.
+	SortKey string
+	// Projection Which attributes are projected into the index. Defaults to types.ProjectionTypeAll.
+	Projection types.ProjectionType
+}
+
+// projectsAll reports whether all the attributes of the table are projected into the index.
+func (i Index) projectsAll() bool {
+	return i.Projection == "" || i.Projection == types.ProjectionTypeAll
+}
+
+// ProjectionError is returned when an item read from an index is missing attributes
+// that the modeler needs, because the index does not project them.
+type ProjectionError struct {
+	// Index The name of the index that was queried.
+	Index string
+	// Missing The required attributes that were absent from the item.
+	Missing []string
+}
+
+func (e *ProjectionError) Error() string {
+	return fmt.Sprintf("index %q does not project attributes required by the modeler: %s", e.Index, strings.Join(e.Missing, ", "))
+}
+
+// missingAttributes returns the attributes that are absent from the item, in the order they are given.
+func missingAttributes(item map[string]types.AttributeValue, attributes []string) []string {
+	var missing []string
+	for _, attr := range attributes {
+		if _, ok := item[attr]; !ok {
+			missing = append(missing, attr)
+		}
+	}
+	return missing
+}
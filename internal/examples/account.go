@@ -0,0 +1,68 @@
+package examples
+
+import (
+	"github.com/bezhermoso/dynamorm"
+)
+
+// AccountModel and MembershipModel share a table, and the partition of their account, in a single-table design.
+// Their items are told apart by their Type attribute, which NewAccountModeler dispatches on.
+type AccountModel struct {
+	ID   string `dynamodbav:"PK" dynamorm:"pk"`
+	Sort string `dynamodbav:"SK" dynamorm:"sk"`
+	Type string `dynamodbav:"Type"`
+	Name string `dynamodbav:"Name"`
+
+	dynamorm.HasConditionExpression
+}
+
+// NewAccount creates the account item of an account.
+func NewAccount(id, name string) *AccountModel {
+	return &AccountModel{ID: id, Sort: "ACCOUNT", Type: "Account", Name: name}
+}
+
+// Item implements dynamorm.Model.
+func (a *AccountModel) Item() interface{} {
+	return a
+}
+
+// Key implements dynamorm.Model.
+func (a *AccountModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(a)
+}
+
+// MembershipModel is a member of an account, stored in the partition of the account.
+type MembershipModel struct {
+	AccountID string `dynamodbav:"PK" dynamorm:"pk"`
+	Sort      string `dynamodbav:"SK" dynamorm:"sk"`
+	Type      string `dynamodbav:"Type"`
+	UserID    string `dynamodbav:"UserID"`
+	Role      string `dynamodbav:"Role"`
+
+	dynamorm.HasConditionExpression
+}
+
+// NewMembership creates the membership item of a user within an account.
+func NewMembership(accountID, userID, role string) *MembershipModel {
+	return &MembershipModel{AccountID: accountID, Sort: "MEMBER#" + userID, Type: "Membership", UserID: userID, Role: role}
+}
+
+// Item implements dynamorm.Model.
+func (m *MembershipModel) Item() interface{} {
+	return m
+}
+
+// Key implements dynamorm.Model.
+func (m *MembershipModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(m)
+}
+
+// NewAccountModeler returns a modeler of the items of account partitions.
+func NewAccountModeler() dynamorm.Modeler[dynamorm.Model] {
+	return dynamorm.NewPolymorphicModeler[dynamorm.Model]("Type").
+		Register("Account", dynamorm.AdaptModeler[dynamorm.Model](dynamorm.StructModeler[*AccountModel]())).
+		Register("Membership", dynamorm.AdaptModeler[dynamorm.Model](dynamorm.StructModeler[*MembershipModel]())).
+		Modeler()
+}
+
+var _ dynamorm.Model = &AccountModel{}
+var _ dynamorm.Model = &MembershipModel{}
@@ -0,0 +1,36 @@
+package examples
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/bezhermoso/dynamorm"
+)
+
+// EventModel is a model whose sort key is a number: events of a stream are sorted by their sequence number.
+type EventModel struct {
+	Stream   string `dynamodbav:"Stream"`
+	Sequence int64  `dynamodbav:"Sequence"`
+	Payload  string `dynamodbav:"Payload"`
+
+	dynamorm.HasConditionExpression
+}
+
+// EventKeySchema is the key schema of the table events are stored in.
+var EventKeySchema = dynamorm.KeySchema{
+	PartitionKey: dynamorm.KeyAttribute{Name: "Stream", Type: types.ScalarAttributeTypeS},
+	SortKey:      dynamorm.KeyAttribute{Name: "Sequence", Type: types.ScalarAttributeTypeN},
+}
+
+// Item implements dynamorm.Model.
+func (m *EventModel) Item() interface{} {
+	return m
+}
+
+// Key implements dynamorm.Model.
+func (m *EventModel) Key() dynamorm.Key {
+	return dynamorm.Key{
+		"Stream":   dynamorm.KeyValue(m.Stream),
+		"Sequence": dynamorm.KeyNumber(m.Sequence),
+	}
+}
+
+var _ dynamorm.Model = &EventModel{}
@@ -0,0 +1,68 @@
+package examples
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/bezhermoso/dynamorm"
+)
+
+// NoteModel is a model that implements lifecycle hooks: it validates and normalizes itself before it is written,
+// and keeps track of whether it was loaded from, or saved to, DynamoDB.
+type NoteModel struct {
+	ID    string `dynamodbav:"PK" dynamorm:"pk"`
+	Title string `dynamodbav:"Title"`
+	Slug  string `dynamodbav:"Slug"`
+
+	// Persisted Whether the note was loaded from, or saved to, DynamoDB.
+	Persisted bool `dynamodbav:"-"`
+
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+func (n *NoteModel) Item() interface{} {
+	return n
+}
+
+// Key implements dynamorm.Model.
+func (n *NoteModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(n)
+}
+
+// BeforeCreate implements dynamorm.BeforeCreate.
+func (n *NoteModel) BeforeCreate(ctx context.Context) error {
+	return n.normalize()
+}
+
+// BeforeUpdate implements dynamorm.BeforeUpdate.
+func (n *NoteModel) BeforeUpdate(ctx context.Context) error {
+	return n.normalize()
+}
+
+// AfterSave implements dynamorm.AfterSave.
+func (n *NoteModel) AfterSave(ctx context.Context) {
+	n.Persisted = true
+}
+
+// AfterLoad implements dynamorm.AfterLoad.
+func (n *NoteModel) AfterLoad(ctx context.Context) error {
+	n.Persisted = true
+	return nil
+}
+
+// normalize rejects notes without a title, and derives the slug from the title.
+func (n *NoteModel) normalize() error {
+	title := strings.TrimSpace(n.Title)
+	if title == "" {
+		return errors.New("title is required")
+	}
+	n.Slug = strings.ToLower(strings.Join(strings.Fields(title), "-"))
+	return nil
+}
+
+var _ dynamorm.BeforeCreate = &NoteModel{}
+var _ dynamorm.BeforeUpdate = &NoteModel{}
+var _ dynamorm.AfterSave = &NoteModel{}
+var _ dynamorm.AfterLoad = &NoteModel{}
@@ -0,0 +1,115 @@
+package examples
+
+import (
+	"fmt"
+
+	"github.com/bezhermoso/dynamorm"
+)
+
+// The order model is the root of an aggregate that spans three levels:
+// an order has line items, and each line item reserves inventory.
+// Saving an order saves the whole aggregate within a single transaction.
+type orderModel struct {
+	ID       string `dynamodbav:"PK" dynamorm:"pk"`
+	Customer string `dynamodbav:"Customer"`
+	Type     string `dynamodbav:"Type"`
+
+	lineItems []*lineItemModel
+
+	dynamorm.HasConditionExpression
+}
+
+func newOrder(id, customer string) *orderModel {
+	return &orderModel{ID: "ORDER#" + id, Customer: customer, Type: "Order"}
+}
+
+// addLineItem adds a line item to the order, which reserves the quantity of the SKU.
+func (o *orderModel) addLineItem(sku string, quantity int) {
+	line := &lineItemModel{
+		ID:       fmt.Sprintf("%s#LINE#%d", o.ID, len(o.lineItems)+1),
+		SKU:      sku,
+		Quantity: quantity,
+		Type:     "LineItem",
+		reservation: &reservationModel{
+			ID:       fmt.Sprintf("SKU#%s#%s", sku, o.ID),
+			Quantity: quantity,
+			Type:     "Reservation",
+		},
+	}
+	o.lineItems = append(o.lineItems, line)
+}
+
+// Item implements dynamorm.Model.
+func (o *orderModel) Item() interface{} {
+	return o
+}
+
+// Key implements dynamorm.Model.
+func (o *orderModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(o)
+}
+
+// Related implements dynamorm.HasRelated.
+func (o *orderModel) Related() ([]dynamorm.Model, error) {
+	related := make([]dynamorm.Model, 0, len(o.lineItems))
+	for _, line := range o.lineItems {
+		related = append(related, line)
+	}
+	return related, nil
+}
+
+// The line item model is related to the order, and has related models of its own.
+type lineItemModel struct {
+	ID       string `dynamodbav:"PK" dynamorm:"pk"`
+	SKU      string `dynamodbav:"SKU"`
+	Quantity int    `dynamodbav:"Quantity"`
+	Type     string `dynamodbav:"Type"`
+
+	reservation *reservationModel
+
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+func (l *lineItemModel) Item() interface{} {
+	return l
+}
+
+// Key implements dynamorm.Model.
+func (l *lineItemModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(l)
+}
+
+// Related implements dynamorm.HasRelated.
+func (l *lineItemModel) Related() ([]dynamorm.Model, error) {
+	return []dynamorm.Model{l.reservation}, nil
+}
+
+// The reservation model is related to a line item, two levels down from the order.
+// Reservations live in the inventory table rather than in the orders table.
+type reservationModel struct {
+	ID       string `dynamodbav:"PK" dynamorm:"pk"`
+	Quantity int    `dynamodbav:"Quantity"`
+	Type     string `dynamodbav:"Type"`
+
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+func (r *reservationModel) Item() interface{} {
+	return r
+}
+
+// Key implements dynamorm.Model.
+func (r *reservationModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(r)
+}
+
+// TableName implements dynamorm.TableNamer.
+func (r *reservationModel) TableName() string {
+	return "inventory"
+}
+
+var _ dynamorm.HasRelated = &orderModel{}
+var _ dynamorm.HasRelated = &lineItemModel{}
+var _ dynamorm.TableNamer = &reservationModel{}
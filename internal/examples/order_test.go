@@ -0,0 +1,288 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/bezhermoso/dynamorm"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func orderPut(table string, item map[string]types.AttributeValue) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			Item:      item,
+			TableName: aws.String(table),
+		},
+	}
+}
+
+func TestCreate_Order(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// The whole aggregate is saved in one transaction, level by level.
+	// Reservations are written to the inventory table.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input: &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							Item: map[string]types.AttributeValue{
+								"PK":       &types.AttributeValueMemberS{Value: "ORDER#1"},
+								"Customer": &types.AttributeValueMemberS{Value: "fherbert"},
+								"Type":     &types.AttributeValueMemberS{Value: "Order"},
+							},
+							TableName:           aws.String("orders"),
+							ConditionExpression: aws.String("attribute_not_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+							},
+						},
+					},
+					orderPut("orders", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "ORDER#1#LINE#1"},
+						"SKU":      &types.AttributeValueMemberS{Value: "spice"},
+						"Quantity": &types.AttributeValueMemberN{Value: "2"},
+						"Type":     &types.AttributeValueMemberS{Value: "LineItem"},
+					}),
+					orderPut("orders", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "ORDER#1#LINE#2"},
+						"SKU":      &types.AttributeValueMemberS{Value: "stillsuit"},
+						"Quantity": &types.AttributeValueMemberN{Value: "1"},
+						"Type":     &types.AttributeValueMemberS{Value: "LineItem"},
+					}),
+					orderPut("inventory", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "SKU#spice#ORDER#1"},
+						"Quantity": &types.AttributeValueMemberN{Value: "2"},
+						"Type":     &types.AttributeValueMemberS{Value: "Reservation"},
+					}),
+					orderPut("inventory", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "SKU#stillsuit#ORDER#1"},
+						"Quantity": &types.AttributeValueMemberN{Value: "1"},
+						"Type":     &types.AttributeValueMemberS{Value: "Reservation"},
+					}),
+				},
+			},
+			Output: &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		Build()
+	assert.Nil(t, err)
+
+	order := newOrder("1", "fherbert")
+	order.addLineItem("spice", 2)
+	order.addLineItem("stillsuit", 1)
+
+	err = repo.Create(context.Background(), order)
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Order_TooDeep(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		WithMaxRelatedDepth(1).
+		Build()
+	assert.Nil(t, err)
+
+	order := newOrder("1", "fherbert")
+	order.addLineItem("spice", 2)
+
+	err = repo.Create(context.Background(), order)
+	assert.ErrorIs(t, err, dynamorm.ErrRelatedTooDeep)
+
+	var relatedErr *dynamorm.RelatedError
+	assert.ErrorAs(t, err, &relatedErr)
+	assert.Same(t, order.lineItems[0], relatedErr.Parent)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Order_Cycle(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		Build()
+	assert.Nil(t, err)
+
+	order := newOrder("1", "fherbert")
+	order.addLineItem("spice", 2)
+	// The line item points back at the order's key.
+	order.lineItems[0].ID = order.ID
+
+	err = repo.Create(context.Background(), order)
+	assert.ErrorIs(t, err, dynamorm.ErrRelatedCycle)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Order_Deduplicated(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input: &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							Item: map[string]types.AttributeValue{
+								"PK":       &types.AttributeValueMemberS{Value: "ORDER#1"},
+								"Customer": &types.AttributeValueMemberS{Value: "fherbert"},
+								"Type":     &types.AttributeValueMemberS{Value: "Order"},
+							},
+							TableName:           aws.String("orders"),
+							ConditionExpression: aws.String("attribute_not_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+							},
+						},
+					},
+					orderPut("orders", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "ORDER#1#LINE#1"},
+						"SKU":      &types.AttributeValueMemberS{Value: "spice"},
+						"Quantity": &types.AttributeValueMemberN{Value: "2"},
+						"Type":     &types.AttributeValueMemberS{Value: "LineItem"},
+					}),
+					orderPut("inventory", map[string]types.AttributeValue{
+						"PK":       &types.AttributeValueMemberS{Value: "SKU#spice#ORDER#1"},
+						"Quantity": &types.AttributeValueMemberN{Value: "2"},
+						"Type":     &types.AttributeValueMemberS{Value: "Reservation"},
+					}),
+				},
+			},
+			Output: &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		Build()
+	assert.Nil(t, err)
+
+	order := newOrder("1", "fherbert")
+	order.addLineItem("spice", 2)
+	// The same line item is listed twice; it is only written once.
+	order.lineItems = append(order.lineItems, order.lineItems[0])
+
+	err = repo.Create(context.Background(), order)
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func newLargeOrder(lines int) *orderModel {
+	order := newOrder("1", "fherbert")
+	for i := 1; i <= lines; i++ {
+		order.addLineItem(fmt.Sprintf("sku-%d", i), 1)
+	}
+	return order
+}
+
+func TestCreate_Order_TooLarge(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		Build()
+	assert.Nil(t, err)
+
+	// 1 order + 60 line items + 60 reservations exceed the 100-item limit.
+	err = repo.Create(context.Background(), newLargeOrder(60))
+	assert.ErrorIs(t, err, dynamorm.ErrTransactionTooLarge)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Order_ChunkedCompensated(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves the first batch: 1 order, 60 line items and 39 reservations, none of which exist yet.
+	stubber.Add(testtools.Stub{
+		OperationName: "TransactGetItems",
+		Input:         &dynamodb.TransactGetItemsInput{},
+		IgnoreFields:  []string{"TransactItems"},
+		Output:        &dynamodb.TransactGetItemsOutput{Responses: make([]types.ItemResponse, 100)},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "TransactWriteItems",
+		Input:         &dynamodb.TransactWriteItemsInput{},
+		IgnoreFields:  []string{"TransactItems"},
+		Output:        &dynamodb.TransactWriteItemsOutput{},
+	})
+
+	// Serves the second batch: the remaining 21 reservations. One of them is out of stock.
+	stubber.Add(testtools.Stub{
+		OperationName: "TransactGetItems",
+		Input:         &dynamodb.TransactGetItemsInput{},
+		IgnoreFields:  []string{"TransactItems"},
+		Output:        &dynamodb.TransactGetItemsOutput{Responses: make([]types.ItemResponse, 21)},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "TransactWriteItems",
+		Error: &testtools.StubError{
+			Err:           &types.TransactionCanceledException{Message: aws.String("Transaction cancelled")},
+			ContinueAfter: true,
+		},
+	})
+
+	// Serves the compensation of the first batch: the items it created are deleted, in reverse order.
+	compensations := []types.TransactWriteItem{}
+	for i := 39; i >= 1; i-- {
+		compensations = append(compensations, orderDelete("inventory", fmt.Sprintf("SKU#sku-%d#ORDER#1", i)))
+	}
+	for i := 60; i >= 1; i-- {
+		compensations = append(compensations, orderDelete("orders", fmt.Sprintf("ORDER#1#LINE#%d", i)))
+	}
+	compensations = append(compensations, orderDelete("orders", "ORDER#1"))
+	stubber.Add(testtools.Stub{
+		OperationName: "TransactWriteItems",
+		Input:         &dynamodb.TransactWriteItemsInput{TransactItems: compensations},
+		Output:        &dynamodb.TransactWriteItemsOutput{},
+	})
+
+	repo, err := dynamorm.NewBuilder[*orderModel]().
+		WithClient(client).
+		WithTableName("orders").
+		WithChunkedTransactions().
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Create(context.Background(), newLargeOrder(60))
+
+	var sagaErr *dynamorm.SagaError
+	assert.ErrorAs(t, err, &sagaErr)
+	assert.Equal(t, 2, sagaErr.Batches)
+	assert.Equal(t, 1, sagaErr.Committed)
+	assert.NoError(t, sagaErr.CompensationErr)
+
+	var txErr *dynamorm.TransactionError
+	assert.ErrorAs(t, err, &txErr)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func orderDelete(table, id string) types.TransactWriteItem {
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: id},
+			},
+			TableName: aws.String(table),
+		},
+	}
+}
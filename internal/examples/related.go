@@ -1,6 +1,7 @@
 package examples
 
 import (
+	"context"
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -150,16 +151,11 @@ func newUserModeler() dynamorm.Modeler[*userModel] {
 	}
 }
 
-// Persisted As the user of the Repository, you should call this method after a successful save operation.
-//
-// The Repository cannot be responsible for this because it may not have the ability to determine
-// which related models were saved or not, in cases where partial failures occur.
-//
-// Here we reset the priorUsernameModel to the username model that was assume was saved.
-// Since we normally only save a model once, this shouldn't be needed in most cases.
-func (u *userModel) Persisted() error {
+// AfterSave implements dynamorm.AfterSave.
+// The repository calls this once the user, along with its username, was actually written. From then on the
+// username is the one associated with the user, so further saves attest that it still is rather than claim it anew.
+func (u *userModel) AfterSave(ctx context.Context) {
 	u.priorUsername = u.dto.Username
-	return nil
 }
 
 // usernameModelFromUserDto creates a username model from a user DTO.
@@ -197,8 +193,9 @@ func conditionExpressionForNewUsername(u *usernameModel) (*expression.Expression
 	// Attests that:
 	//  1.) The username is not taken: username item w/ PK = username does not exist.
 	// The dynamorm.Key type already has a convience method for this.
-	return u.Key().CondtionExpressionForCreate()
+	return u.Key().ConditionExpressionForCreate()
 }
 
 var _ dynamorm.HasRelated = &userModel{}
+var _ dynamorm.AfterSave = &userModel{}
 var _ dynamorm.Model = &usernameModel{}
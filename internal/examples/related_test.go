@@ -6,7 +6,9 @@ import (
 
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go/aws"
@@ -251,13 +253,314 @@ func TestUpdate_Related(t *testing.T) {
 	err = repo.Update(context.Background(), model)
 	assert.NoError(t, err)
 
-	// NOTE: This is just our way to "reset" the model's internal state so we can test the next update.
-	// In most scenarios this isn't needed e.g. a model is updated once per request & discarded.
-	//
-	// I'd like to solve this in a better way in the future (e.g. "reset" based on context.Context state?)
-	_ = model.Persisted()
+	// The username was saved: the next update attests that it is still associated with the user.
+	assert.Equal(t, "jappleseed", model.priorUsername)
 
 	model.dto.Name = "John Appleseed, Sr."
 	err = repo.Update(context.Background(), model)
 	assert.NoError(t, err)
 }
+
+func TestDeleteModel_Related(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves repo.Get()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "GetItem",
+			Input: &dynamodb.GetItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "001"},
+				},
+				TableName: aws.String("users"),
+			},
+			Output: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"PK":       &types.AttributeValueMemberS{Value: "001"},
+					"Name":     &types.AttributeValueMemberS{Value: "John Appleseed"},
+					"Username": &types.AttributeValueMemberS{Value: "jappleseed"},
+					"Type":     &types.AttributeValueMemberS{Value: "User"},
+				},
+			},
+		},
+	)
+
+	// Serves repo.DeleteModel()
+	// Deletes the user along with the username item that it owns.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input: &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Delete: &types.Delete{
+							Key: map[string]types.AttributeValue{
+								"PK": &types.AttributeValueMemberS{Value: "001"},
+							},
+							ConditionExpression: aws.String("attribute_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+							},
+							TableName: aws.String("users"),
+						},
+					},
+					{
+						Delete: &types.Delete{
+							Key: map[string]types.AttributeValue{
+								"PK": &types.AttributeValueMemberS{Value: "jappleseed"},
+							},
+							ConditionExpression: aws.String("(attribute_exists (#0)) AND (#1 = :0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+								"#1": "UserId",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":0": &types.AttributeValueMemberS{Value: "001"},
+							},
+							TableName: aws.String("users"),
+						},
+					},
+				},
+			},
+			Output: &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	model, err := repo.Get(context.Background(), dynamorm.Key{
+		"PK": dynamorm.KeyValue("001"),
+	})
+	assert.Nil(t, err)
+
+	err = repo.DeleteModel(context.Background(), model)
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestPatchModel_Related(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves repo.PatchModel()
+	// Updates the user's name, while attesting that the username item still belongs to the user.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input: &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Update: &types.Update{
+							Key: map[string]types.AttributeValue{
+								"PK": &types.AttributeValueMemberS{Value: "001"},
+							},
+							UpdateExpression:    aws.String("SET #1 = :0\n"),
+							ConditionExpression: aws.String("attribute_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+								"#1": "Name",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":0": &types.AttributeValueMemberS{Value: "John Appleseed, Sr."},
+							},
+							TableName: aws.String("users"),
+						},
+					},
+					{
+						Put: &types.Put{
+							Item: map[string]types.AttributeValue{
+								"PK":     &types.AttributeValueMemberS{Value: "jappleseed"},
+								"UserId": &types.AttributeValueMemberS{Value: "001"},
+								"Type":   &types.AttributeValueMemberS{Value: "Username"},
+							},
+							ConditionExpression: aws.String("(attribute_exists (#0)) AND (#1 = :0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+								"#1": "UserId",
+							},
+							ExpressionAttributeValues: map[string]types.AttributeValue{
+								":0": &types.AttributeValueMemberS{Value: "001"},
+							},
+							TableName: aws.String("users"),
+						},
+					},
+				},
+			},
+			Output: &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	// As if the user was loaded with its username.
+	model := newWithDetails("001", "John Appleseed", "jappleseed")
+	model.priorUsername = model.dto.Username
+
+	update := expression.Set(expression.Name("Name"), expression.Value("John Appleseed, Sr."))
+	err = repo.PatchModel(context.Background(), model, update)
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Related_UsernameTaken(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Error: &testtools.StubError{
+				Err: &types.TransactionCanceledException{
+					Message: aws.String("Transaction cancelled"),
+					CancellationReasons: []types.CancellationReason{
+						{Code: aws.String("None")},
+						{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed")},
+					},
+				},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	model := newWithDetails("002", "Frank Herbert", "fherbert")
+	err = repo.Create(context.Background(), model)
+
+	// Nothing was saved: the username is still not associated with the user.
+	assert.Empty(t, model.priorUsername)
+
+	// The username item is the one that failed, not the user item.
+	assert.ErrorIs(t, err, dynamorm.ErrConditionFailed)
+	assert.NotErrorIs(t, err, dynamorm.ErrAlreadyExists)
+
+	var txErr *dynamorm.TransactionError
+	assert.ErrorAs(t, err, &txErr)
+	assert.Len(t, txErr.Failures, 1)
+	assert.Equal(t, 1, txErr.Failures[0].Index)
+	assert.False(t, txErr.Failures[0].Primary)
+	assert.IsType(t, &usernameModel{}, txErr.Failures[0].Model)
+	assert.Equal(t, "fherbert", txErr.Failures[0].Model.(*usernameModel).Username)
+}
+
+func TestUpdate_Related_UsernameChanged(t *testing.T) {
+	// No stubs: nothing should be written.
+	client, stubber := newStubbedClient()
+
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	// As if the user was loaded with its username.
+	model := newWithDetails("001", "John Appleseed", "jappleseed")
+	model.priorUsername = model.dto.Username
+	model.dto.Username = "jseed"
+
+	err = repo.Update(context.Background(), model)
+	var relatedErr *dynamorm.RelatedError
+	assert.ErrorAs(t, err, &relatedErr)
+	assert.Same(t, model, relatedErr.Parent)
+	assert.Nil(t, relatedErr.Related)
+	assert.EqualError(t, relatedErr.Err, "username cannot be changed")
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestCreate_Related_RelatedTable(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input: &dynamodb.TransactWriteItemsInput{
+				TransactItems: []types.TransactWriteItem{
+					{
+						Put: &types.Put{
+							Item: map[string]types.AttributeValue{
+								"PK":       &types.AttributeValueMemberS{Value: "002"},
+								"Username": &types.AttributeValueMemberS{Value: "fherbert"},
+								"Name":     &types.AttributeValueMemberS{Value: "Frank Herbert"},
+								"Type":     &types.AttributeValueMemberS{Value: "User"},
+							},
+							TableName:           aws.String("users"),
+							ConditionExpression: aws.String("attribute_not_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+							},
+						},
+					},
+					{
+						Put: &types.Put{
+							Item: map[string]types.AttributeValue{
+								"PK":     &types.AttributeValueMemberS{Value: "fherbert"},
+								"UserId": &types.AttributeValueMemberS{Value: "002"},
+								"Type":   &types.AttributeValueMemberS{Value: "Username"},
+							},
+							// Usernames are registered to live in their own table.
+							TableName:           aws.String("usernames"),
+							ConditionExpression: aws.String("attribute_not_exists (#0)"),
+							ExpressionAttributeNames: map[string]string{
+								"#0": "PK",
+							},
+						},
+					},
+				},
+			},
+			Output: &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		WithRelatedTable(&usernameModel{}, "usernames").
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Create(context.Background(), newWithDetails("002", "Frank Herbert", "fherbert"))
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestRelated_UsernameUniqueness(t *testing.T) {
+	// Runs against an in-memory table rather than stubs, to exercise the behavior of the username items.
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "users", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*userModel]().
+		WithClient(client).
+		WithTableName("users").
+		WithModeler(newUserModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	john := newWithDetails("001", "John Appleseed", "jappleseed")
+	assert.NoError(t, repo.Create(ctx, john))
+	assert.NotNil(t, client.Item("users", dynamorm.Key{"PK": dynamorm.KeyValue("jappleseed")}))
+
+	// The username is taken: nothing is written.
+	impostor := newWithDetails("002", "Jane Appleseed", "jappleseed")
+	assert.ErrorIs(t, repo.Create(ctx, impostor), dynamorm.ErrConditionFailed)
+	assert.Len(t, client.Items("users"), 2)
+
+	// Updates attest that the username is still the user's.
+	john.dto.Name = "John Appleseed, Sr."
+	assert.NoError(t, repo.Update(ctx, john))
+
+	loaded, err := repo.Get(ctx, john.Key())
+	assert.NoError(t, err)
+	assert.Equal(t, "John Appleseed, Sr.", loaded.dto.Name)
+	assert.NoError(t, repo.Update(ctx, loaded))
+}
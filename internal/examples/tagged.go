@@ -0,0 +1,27 @@
+package examples
+
+import "github.com/bezhermoso/dynamorm"
+
+// TaggedModel is a model that needs no modeler nor hand-written key: its key attributes are derived from its
+// `dynamorm` struct tags, and the repository unmarshals items straight into it.
+type TaggedModel struct {
+	Team   string `dynamodbav:"PK" dynamorm:"pk"`
+	Member string `dynamodbav:"SK" dynamorm:"sk"`
+	Role   string `dynamodbav:"Role"`
+
+	// Embed the HasConditionExpression to get the convenience of setting and getting the condition expression.
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+// Returns itself, as it holds the attributes themselves.
+func (m *TaggedModel) Item() interface{} {
+	return m
+}
+
+// Key implements dynamorm.Model.
+func (m *TaggedModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(m)
+}
+
+var _ dynamorm.Model = &TaggedModel{}
@@ -0,0 +1,27 @@
+package examples
+
+import "github.com/bezhermoso/dynamorm"
+
+// ProfileModel is a model that opts into change tracking by embedding dynamorm.Tracked.
+// Once loaded through the repository, updates only write the attributes that changed.
+type ProfileModel struct {
+	ID       string `dynamodbav:"PK" dynamorm:"pk"`
+	Name     string `dynamodbav:"Name"`
+	Bio      string `dynamodbav:"Bio,omitempty"`
+	Location string `dynamodbav:"Location"`
+
+	dynamorm.Tracked
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+func (p *ProfileModel) Item() interface{} {
+	return p
+}
+
+// Key implements dynamorm.Model.
+func (p *ProfileModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(p)
+}
+
+var _ dynamorm.Model = &ProfileModel{}
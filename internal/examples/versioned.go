@@ -0,0 +1,29 @@
+package examples
+
+import "github.com/bezhermoso/dynamorm"
+
+// DocumentModel is a model that opts into optimistic locking by implementing dynamorm.Versioned.
+type DocumentModel struct {
+	ID      string `dynamodbav:"PK" dynamorm:"pk"`
+	Body    string `dynamodbav:"Body"`
+	Version int64  `dynamodbav:"Version"`
+
+	dynamorm.HasConditionExpression
+}
+
+// Item implements dynamorm.Model.
+func (d *DocumentModel) Item() interface{} {
+	return d
+}
+
+// Key implements dynamorm.Model.
+func (d *DocumentModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(d)
+}
+
+// VersionAttribute implements dynamorm.Versioned.
+func (d *DocumentModel) VersionAttribute() string {
+	return "Version"
+}
+
+var _ dynamorm.Versioned = &DocumentModel{}
@@ -0,0 +1,159 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Number is the set of Go types that KeyNumber accepts.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// KeyNumber is a helper function that constructs a number attribute value, e.g. for timestamps or sequence
+// numbers used as sort keys.
+func KeyNumber[N Number](value N) types.AttributeValue {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return &types.AttributeValueMemberN{Value: strconv.FormatInt(v.Int(), 10)}
+	case v.CanUint():
+		return &types.AttributeValueMemberN{Value: strconv.FormatUint(v.Uint(), 10)}
+	default:
+		return &types.AttributeValueMemberN{Value: strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())}
+	}
+}
+
+// KeyBinary is a helper function that constructs a binary attribute value.
+func KeyBinary(value []byte) types.AttributeValue {
+	return &types.AttributeValueMemberB{Value: value}
+}
+
+// KeyAttribute is the name and type of a key attribute.
+type KeyAttribute struct {
+	// Name The name of the attribute.
+	Name string
+	// Type The type of the attribute: S, N or B.
+	Type types.ScalarAttributeType
+}
+
+// KeySchema describes the primary key of a table: its partition key, and its sort key if any.
+// Set it with Builder.WithKeySchema to have keys checked before any request is sent.
+type KeySchema struct {
+	// PartitionKey The partition (hash) key attribute.
+	PartitionKey KeyAttribute
+	// SortKey The sort (range) key attribute. Left zero if the table has no sort key.
+	SortKey KeyAttribute
+}
+
+// Validate checks that the key has exactly the attributes of the schema, with the types of the schema.
+// Fails with ErrInvalidKey, describing every problem found, if it does not.
+func (s KeySchema) Validate(key Key) error {
+	var problems []string
+	expected := map[string]bool{}
+	for _, attr := range s.attributes() {
+		expected[attr.Name] = true
+		value, ok := key[attr.Name]
+		if !ok || value == nil {
+			problems = append(problems, fmt.Sprintf("attribute %q is missing", attr.Name))
+			continue
+		}
+		if typ := scalarAttributeType(value); typ != attr.Type {
+			problems = append(problems, fmt.Sprintf("attribute %q must be of type %s, not %s", attr.Name, attr.Type, describeAttributeType(value)))
+		}
+	}
+	var unexpected []string
+	for name := range key {
+		if !expected[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	sort.Strings(unexpected)
+	for _, name := range unexpected {
+		problems = append(problems, fmt.Sprintf("attribute %q is not part of the key", name))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidKey, key, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateQuery checks that a query against the table names the key attributes of the schema, and that its
+// partition key value has the type of the schema.
+func (s KeySchema) validateQuery(query *Query) error {
+	var problems []string
+	if query.partitionKey != s.PartitionKey.Name {
+		problems = append(problems, fmt.Sprintf("the table is partitioned by %q, not %q", s.PartitionKey.Name, query.partitionKey))
+	} else if query.partitionValue != nil && scalarAttributeType(query.partitionValue) != s.PartitionKey.Type {
+		problems = append(problems, fmt.Sprintf("attribute %q must be of type %s, not %s",
+			s.PartitionKey.Name, s.PartitionKey.Type, describeAttributeType(query.partitionValue)))
+	}
+	if query.sortKey != nil && query.sortKey.name != s.SortKey.Name {
+		problems = append(problems, fmt.Sprintf("the table is sorted by %q, not %q", s.SortKey.Name, query.sortKey.name))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidKey, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// attributes returns the attributes of the schema, partition key first.
+func (s KeySchema) attributes() []KeyAttribute {
+	if s.SortKey.Name == "" {
+		return []KeyAttribute{s.PartitionKey}
+	}
+	return []KeyAttribute{s.PartitionKey, s.SortKey}
+}
+
+// problems reports what makes the schema itself invalid.
+func (s KeySchema) problems() []string {
+	var problems []string
+	if s.PartitionKey.Name == "" {
+		problems = append(problems, "the key schema requires a partition key (WithKeySchema)")
+	}
+	for _, attr := range s.attributes() {
+		if attr.Name == "" {
+			continue
+		}
+		switch attr.Type {
+		case types.ScalarAttributeTypeS, types.ScalarAttributeTypeN, types.ScalarAttributeTypeB:
+		default:
+			problems = append(problems, fmt.Sprintf("key attribute %q must be of type S, N or B (WithKeySchema)", attr.Name))
+		}
+	}
+	if s.SortKey.Name == "" && s.SortKey.Type != "" {
+		problems = append(problems, "the sort key of the key schema requires a name (WithKeySchema)")
+	}
+	if s.SortKey.Name != "" && s.SortKey.Name == s.PartitionKey.Name {
+		problems = append(problems, "the partition and sort keys of the key schema must differ (WithKeySchema)")
+	}
+	return problems
+}
+
+// scalarAttributeType returns the type of a scalar attribute value, or an empty type if it is not a scalar.
+func scalarAttributeType(value types.AttributeValue) types.ScalarAttributeType {
+	switch value.(type) {
+	case *types.AttributeValueMemberS:
+		return types.ScalarAttributeTypeS
+	case *types.AttributeValueMemberN:
+		return types.ScalarAttributeTypeN
+	case *types.AttributeValueMemberB:
+		return types.ScalarAttributeTypeB
+	}
+	return ""
+}
+
+// describeAttributeType names the type of an attribute value for error messages.
+func describeAttributeType(value types.AttributeValue) string {
+	if typ := scalarAttributeType(value); typ != "" {
+		return string(typ)
+	}
+	return fmt.Sprintf("%T", value)
+}
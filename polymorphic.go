@@ -0,0 +1,99 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// PolymorphicModeler converts items into models of different types, for tables that hold several kinds of items,
+// e.g. users, usernames and memberships within a single partition. It dispatches on a discriminator attribute: the
+// modeler registered for the value of the attribute converts the item.
+//
+// T is usually an interface that the models have in common, e.g. Model itself:
+//
+//	modeler := dynamorm.NewPolymorphicModeler[dynamorm.Model]("Type").
+//		Register("User", dynamorm.AdaptModeler[dynamorm.Model](dynamorm.StructModeler[*User]())).
+//		Register("Membership", dynamorm.AdaptModeler[dynamorm.Model](dynamorm.StructModeler[*Membership]()))
+//
+//	repo, err := dynamorm.NewBuilder[dynamorm.Model]().WithModeler(modeler.Modeler()) // ...
+//
+// Items whose discriminator is missing, or has no registered modeler, fail with IncompatibleModelerError. See
+// Builder.WithUnmatchedItems to skip or report them instead.
+type PolymorphicModeler[T Model] struct {
+	attribute string
+	modelers  map[string]Modeler[T]
+}
+
+// NewPolymorphicModeler creates a modeler that dispatches on the string attribute with the given name.
+func NewPolymorphicModeler[T Model](attribute string) *PolymorphicModeler[T] {
+	return &PolymorphicModeler[T]{
+		attribute: attribute,
+		modelers:  make(map[string]Modeler[T]),
+	}
+}
+
+// Register sets the modeler of the items whose discriminator attribute has the value.
+func (p *PolymorphicModeler[T]) Register(value string, modeler Modeler[T]) *PolymorphicModeler[T] {
+	p.modelers[value] = modeler
+	return p
+}
+
+// Modeler returns the modeler, to be set with Builder.WithModeler.
+func (p *PolymorphicModeler[T]) Modeler() Modeler[T] {
+	return func(item map[string]types.AttributeValue) (T, error) {
+		var zero T
+		discriminator, ok := item[p.attribute].(*types.AttributeValueMemberS)
+		if !ok {
+			return zero, fmt.Errorf("%w: no string %q attribute", IncompatibleModelerError, p.attribute)
+		}
+		modeler, ok := p.modelers[discriminator.Value]
+		if !ok {
+			return zero, fmt.Errorf("%w: %q is %q, expected one of %q", IncompatibleModelerError, p.attribute, discriminator.Value, p.values())
+		}
+		return modeler(item)
+	}
+}
+
+// values returns the registered values of the discriminator attribute, sorted.
+func (p *PolymorphicModeler[T]) values() []string {
+	values := make([]string, 0, len(p.modelers))
+	for value := range p.modelers {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// AdaptModeler adapts the modeler of a model type M into a modeler of T, an interface that M implements, so that it
+// can be registered with a PolymorphicModeler. Fails if the models of M do not implement T.
+func AdaptModeler[T Model, M Model](modeler Modeler[M]) Modeler[T] {
+	return func(item map[string]types.AttributeValue) (T, error) {
+		var zero T
+		m, err := modeler(item)
+		if err != nil {
+			return zero, err
+		}
+		t, ok := Model(m).(T)
+		if !ok {
+			return zero, fmt.Errorf("%T does not implement %v", m, reflect.TypeOf((*T)(nil)).Elem())
+		}
+		return t, nil
+	}
+}
+
+// UnmatchedPolicy is how reads handle the items that the modeler does not support, i.e. for which it fails with
+// IncompatibleModelerError.
+type UnmatchedPolicy int
+
+const (
+	// FailUnmatched Fails the read. This is the default.
+	FailUnmatched UnmatchedPolicy = iota
+	// SkipUnmatched Leaves the items out of the results.
+	SkipUnmatched
+	// ReportUnmatched Leaves the items out of the models of the results, and reports them separately, e.g. in
+	// QueryResult.Unmatched.
+	ReportUnmatched
+)
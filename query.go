@@ -0,0 +1,235 @@
+package dynamorm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Query describes a single-page query against a partition of the table.
+// Construct it with NewQuery, and refine it with the With* methods.
+type Query struct {
+	index          string
+	partitionKey   string
+	partitionValue types.AttributeValue
+	sortKey        *SortKeyCondition
+	filter         *expression.ConditionBuilder
+	limit          *int32
+	descending     bool
+	startKey       Key
+	cursor         string
+}
+
+// NewQuery constructs a query for the items whose partition key attribute equals the given value.
+func NewQuery(partitionKey string, value types.AttributeValue) *Query {
+	return &Query{
+		partitionKey:   partitionKey,
+		partitionValue: value,
+	}
+}
+
+// OnIndex makes the query run against a secondary index declared with Builder.WithIndex, instead of the table.
+func (q *Query) OnIndex(name string) *Query {
+	q.index = name
+	return q
+}
+
+// WithSortKey narrows the query down by a condition on the sort key.
+func (q *Query) WithSortKey(condition SortKeyCondition) *Query {
+	q.sortKey = &condition
+	return q
+}
+
+// WithFilter applies a filter expression to the items read by the query.
+// Note that filtered-out items still count towards the limit.
+func (q *Query) WithFilter(filter expression.ConditionBuilder) *Query {
+	q.filter = &filter
+	return q
+}
+
+// WithLimit sets the maximum number of items to evaluate.
+func (q *Query) WithLimit(limit int32) *Query {
+	q.limit = &limit
+	return q
+}
+
+// Descending makes the query return items in descending sort key order.
+func (q *Query) Descending() *Query {
+	q.descending = true
+	return q
+}
+
+// WithStartKey resumes the query after the given key, usually the LastEvaluatedKey of a previous page.
+func (q *Query) WithStartKey(key Key) *Query {
+	q.startKey = key
+	return q
+}
+
+// WithCursor resumes the query from a cursor returned by QueryResult.Cursor.
+func (q *Query) WithCursor(cursor string) *Query {
+	q.cursor = cursor
+	return q
+}
+
+// exclusiveStartKey resolves the start key of the query from either the start key or the cursor.
+func (q *Query) exclusiveStartKey() (Key, error) {
+	if q.startKey != nil {
+		return q.startKey, nil
+	}
+	if q.cursor == "" {
+		return nil, nil
+	}
+	return DecodeCursor(q.cursor)
+}
+
+// expression constructs the key condition and filter expressions of the query, along with the projection
+// expression of the attributes, if any.
+func (q *Query) expression(projection []string) (*expression.Expression, error) {
+	if q.partitionKey == "" || q.partitionValue == nil {
+		return nil, errors.New("partition key is required")
+	}
+	keyCondition := expression.Key(q.partitionKey).Equal(expression.Value(q.partitionValue))
+	if q.sortKey != nil {
+		keyCondition = expression.KeyAnd(keyCondition, q.sortKey.builder)
+	}
+	exprBuilder := expression.NewBuilder().WithKeyCondition(keyCondition)
+	if q.filter != nil {
+		exprBuilder = exprBuilder.WithFilter(*q.filter)
+	}
+	if len(projection) > 0 {
+		exprBuilder = exprBuilder.WithProjection(projectionBuilder(projection))
+	}
+	expr, err := exprBuilder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// SortKeyCondition is a condition on the sort key of a query.
+type SortKeyCondition struct {
+	name    string
+	builder expression.KeyConditionBuilder
+}
+
+// SortKeyEqual matches items whose sort key equals the value.
+func SortKeyEqual(name string, value types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).Equal(expression.Value(value))}
+}
+
+// SortKeyBeginsWith matches items whose sort key starts with the prefix.
+func SortKeyBeginsWith(name string, prefix string) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).BeginsWith(prefix)}
+}
+
+// SortKeyBetween matches items whose sort key is between lower and upper, inclusive.
+func SortKeyBetween(name string, lower, upper types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).Between(expression.Value(lower), expression.Value(upper))}
+}
+
+// SortKeyLessThan matches items whose sort key is less than the value.
+func SortKeyLessThan(name string, value types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).LessThan(expression.Value(value))}
+}
+
+// SortKeyLessThanEqual matches items whose sort key is less than or equal to the value.
+func SortKeyLessThanEqual(name string, value types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).LessThanEqual(expression.Value(value))}
+}
+
+// SortKeyGreaterThan matches items whose sort key is greater than the value.
+func SortKeyGreaterThan(name string, value types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).GreaterThan(expression.Value(value))}
+}
+
+// SortKeyGreaterThanEqual matches items whose sort key is greater than or equal to the value.
+func SortKeyGreaterThanEqual(name string, value types.AttributeValue) SortKeyCondition {
+	return SortKeyCondition{name, expression.Key(name).GreaterThanEqual(expression.Value(value))}
+}
+
+// QueryResult is a single page of models returned by Repository.Query.
+type QueryResult[T Model] struct {
+	// Items The models in the page, in the order returned by DynamoDB.
+	Items []T
+	// LastEvaluatedKey The key to resume the query from. Nil when there are no more pages.
+	LastEvaluatedKey Key
+	// Unmatched The items that the modeler does not support, in the order returned by DynamoDB.
+	// Only set with ReportUnmatched.
+	Unmatched []map[string]types.AttributeValue
+}
+
+// HasMore reports whether there are more pages after this one.
+func (r *QueryResult[T]) HasMore() bool {
+	return len(r.LastEvaluatedKey) > 0
+}
+
+// Cursor returns an opaque, URL-safe token for the next page, or an empty string if there are no more pages.
+func (r *QueryResult[T]) Cursor() (string, error) {
+	if !r.HasMore() {
+		return "", nil
+	}
+	return EncodeCursor(r.LastEvaluatedKey)
+}
+
+// cursorValue is the serialized form of a key attribute value in a cursor.
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// EncodeCursor encodes a key into an opaque, URL-safe token.
+// Only string, number and binary attribute values are supported, as those are the only valid key types.
+func EncodeCursor(key Key) (string, error) {
+	values := make(map[string]cursorValue, len(key))
+	for k, v := range key {
+		switch av := v.(type) {
+		case *types.AttributeValueMemberS:
+			values[k] = cursorValue{Type: "S", Value: av.Value}
+		case *types.AttributeValueMemberN:
+			values[k] = cursorValue{Type: "N", Value: av.Value}
+		case *types.AttributeValueMemberB:
+			values[k] = cursorValue{Type: "B", Value: base64.StdEncoding.EncodeToString(av.Value)}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type %T for %q", v, k)
+		}
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor decodes a token produced by EncodeCursor back into a key.
+func DecodeCursor(cursor string) (Key, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	values := map[string]cursorValue{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	key := make(Key, len(values))
+	for k, v := range values {
+		switch v.Type {
+		case "S":
+			key[k] = &types.AttributeValueMemberS{Value: v.Value}
+		case "N":
+			key[k] = &types.AttributeValueMemberN{Value: v.Value}
+		case "B":
+			b, err := base64.StdEncoding.DecodeString(v.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			key[k] = &types.AttributeValueMemberB{Value: b}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return key, nil
+}
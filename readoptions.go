@@ -0,0 +1,91 @@
+package dynamorm
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// ReadOption configures a read made by Repository.Get, Repository.BatchGet or Repository.Query.
+type ReadOption func(*readOptions)
+
+// readOptions is the configuration of a read.
+type readOptions struct {
+	// consistent Whether the read is strongly consistent.
+	consistent bool
+	// projection The attributes to read. Nil to read whole items.
+	projection []string
+}
+
+// ConsistentRead makes the read strongly consistent, rather than eventually consistent.
+// Note that global secondary indexes do not support consistent reads.
+func ConsistentRead() ReadOption {
+	return func(o *readOptions) {
+		o.consistent = true
+	}
+}
+
+// Project only reads the given attributes of the items, rather than whole items.
+//
+// The key attributes, the version attribute and the attributes required by the modeler are read too, when they are
+// known. Queries fail with ErrInvalidConfiguration if the key attributes of the table are not known, i.e. neither
+// declared with Builder.WithKeySchema nor returned by the Key() of a zero-value model.
+//
+// The modeler is given the partial items, so attributes that were not read are absent from them. Models that embed
+// Tracked remember which attributes were read: see Tracked.Loaded. Repository.Update then only writes the attributes
+// that were read, and fails with ErrPartialModel should any other attribute have changed. Repository.Save and
+// Repository.BatchPut refuse to write partial models with ErrPartialModel.
+func Project(attributes ...string) ReadOption {
+	return func(o *readOptions) {
+		o.projection = append(o.projection, attributes...)
+	}
+}
+
+// newReadOptions applies the options.
+func newReadOptions(opts []ReadOption) readOptions {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// projectedAttributes returns the attributes to read given the options, along with the ones the repository needs:
+// the given key attributes, the key attributes of the table if known, the version attribute and the attributes
+// required by the modeler. Returns nil if whole items are read.
+func (r *repositoryImpl[T]) projectedAttributes(o readOptions, keyAttributes ...string) []string {
+	if o.projection == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var attributes []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				attributes = append(attributes, name)
+			}
+		}
+	}
+	add(o.projection...)
+	add(keyAttributes...)
+	if r.keySchema != nil {
+		add(r.keySchema.PartitionKey.Name, r.keySchema.SortKey.Name)
+	} else {
+		// Which key attribute is which does not matter here.
+		add(r.modelKeyAttributes...)
+	}
+	add(r.modelVersionAttr)
+	add(r.required...)
+	sort.Strings(attributes)
+	return attributes
+}
+
+// projectionBuilder builds the projection expression of the attributes.
+func projectionBuilder(attributes []string) expression.ProjectionBuilder {
+	names := make([]expression.NameBuilder, 0, len(attributes))
+	for _, attr := range attributes {
+		names = append(names, expression.Name(attr))
+	}
+	return expression.NamesList(names[0], names[1:]...)
+}
@@ -0,0 +1,100 @@
+package dynamorm
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+)
+
+// DefaultMaxRelatedDepth is how many levels of related models are walked, unless configured otherwise with
+// Builder.WithMaxRelatedDepth. The direct relations of a model are 1 level deep.
+const DefaultMaxRelatedDepth = 4
+
+// walkRelated visits the related models of a model breadth-first, recursing into the related models that have
+// related models of their own, up to r.maxRelatedDepth levels deep.
+//
+// Models are deduplicated by table and key: only the first model with a given table and key is visited.
+// A related model with the same table and key as one of its ancestors is a cycle, and is reported as ErrRelatedCycle.
+// Errors are wrapped in a RelatedError that identifies which model failed.
+func (r *repositoryImpl[T]) walkRelated(model Model, visit func(rel Model) error) error {
+	type node struct {
+		model     Model
+		depth     int
+		ancestors []string
+	}
+
+	rootID := identify(r.tableName, model)
+	seen := map[string]bool{rootID: true}
+	queue := []node{{model: model, ancestors: []string{rootID}}}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+
+		// Check if the model type supports related models.
+		hasRelated, ok := n.model.(HasRelated)
+		if !ok {
+			continue
+		}
+		relatedModels, err := hasRelated.Related()
+		if err != nil {
+			return &RelatedError{Parent: n.model, Index: -1, Err: err}
+		}
+		if len(relatedModels) > 0 && n.depth >= r.maxRelatedDepth {
+			return &RelatedError{Parent: n.model, Index: -1, Err: ErrRelatedTooDeep}
+		}
+
+		for i, rel := range relatedModels {
+			if rel == nil {
+				return &RelatedError{Parent: n.model, Index: i, Err: errors.New("related model is nil")}
+			}
+			if key := rel.Key(); key == nil || len(key) == 0 {
+				return &RelatedError{Parent: n.model, Related: rel, Index: i, Err: errors.New("key is required")}
+			}
+			id := identify(r.tableNameFor(rel), rel)
+			if slices.Contains(n.ancestors, id) {
+				return &RelatedError{Parent: n.model, Related: rel, Index: i, Err: ErrRelatedCycle}
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if err := visit(rel); err != nil {
+				return &RelatedError{Parent: n.model, Related: rel, Index: i, Err: err}
+			}
+			queue = append(queue, node{
+				model:     rel,
+				depth:     n.depth + 1,
+				ancestors: append(slices.Clip(n.ancestors), id),
+			})
+		}
+	}
+	return nil
+}
+
+// identify returns a string that uniquely identifies the item of a model, by table and key.
+func identify(tableName *string, model Model) string {
+	return *tableName + "\x00" + model.Key().String()
+}
+
+// TableNamer is an optional interface that related models can implement to be written to a table other than
+// the repository's, e.g. uniqueness or audit items that live in their own table.
+// Alternatively, the table of a related model type can be registered with Builder.WithRelatedTable.
+type TableNamer interface {
+	// TableName returns the name of the table the model is written to.
+	TableName() string
+}
+
+// tableNameFor returns the name of the table that a related model is written to.
+// The model's own TableName() takes precedence over the table registered for its type, which takes precedence over
+// the repository's table.
+func (r *repositoryImpl[T]) tableNameFor(model Model) *string {
+	if namer, ok := model.(TableNamer); ok {
+		if name := namer.TableName(); name != "" {
+			return &name
+		}
+	}
+	if name, ok := r.relatedTables[reflect.TypeOf(model)]; ok {
+		return &name
+	}
+	return r.tableName
+}
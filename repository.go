@@ -3,19 +3,60 @@ package dynamorm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 type repositoryImpl[T Model] struct {
 	// The DynamoDB client.
-	client *dynamodb.Client
+	client DynamoDBAPI
 	// The name of the DynamoDB table.
 	tableName *string
 	// The function that converts a map of attribute values into a Model instance.
 	modeler Modeler[T]
+	// The secondary indexes that can be queried, by name.
+	indexes map[string]Index
+	// The attributes that the modeler needs in order to construct a model.
+	required []string
+	// The name of the version attribute used for optimistic locking, if any.
+	versionAttr string
+	// The name of the version attribute of the models of T, which is versionAttr unless they implement Versioned.
+	modelVersionAttr string
+	// The names of the key attributes returned by the Key() of a zero-value model, if it can be called.
+	modelKeyAttributes []string
+	// How many levels of related models are walked.
+	maxRelatedDepth int
+	// The names of the tables that related models are written to, by model type.
+	relatedTables map[reflect.Type]string
+	// Whether writes that exceed the limits of a transaction are split into several transactions.
+	chunked bool
+	// How unprocessed keys and items of batch operations are retried.
+	retryPolicy RetryPolicy
+	// How many batch write requests are in flight at once.
+	batchConcurrency int
+	// The key schema that keys are checked against, if any.
+	keySchema *KeySchema
+	// The function that derives the idempotency token of writes from their context, if any.
+	tokenProvider func(ctx context.Context) string
+	// How reads handle items that the modeler does not support.
+	unmatched UnmatchedPolicy
+}
+
+// checkKey checks that a key is set and, if the repository has a key schema, that it conforms to it.
+func (r *repositoryImpl[T]) checkKey(key Key) error {
+	if key == nil || len(key) == 0 {
+		return errors.New("key is required")
+	}
+	if r.keySchema != nil {
+		return r.keySchema.Validate(key)
+	}
+	return nil
 }
 
 // Modeler is a function that converts a map of attribute values into a model.
@@ -23,13 +64,30 @@ type repositoryImpl[T Model] struct {
 type Modeler[T Model] func(item map[string]types.AttributeValue) (T, error)
 
 // TransactSaveMany implements Repository.
-func (r *repositoryImpl[T]) Get(ctx context.Context, key Key) (T, error) {
+func (r *repositoryImpl[T]) Get(ctx context.Context, key Key, opts ...ReadOption) (T, error) {
 	// Zero value of T.
 	var result T
-	out, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+	if err := r.checkKey(key); err != nil {
+		return result, err
+	}
+	o := newReadOptions(opts)
+	input := &dynamodb.GetItemInput{
 		Key:       key,
 		TableName: r.tableName,
-	})
+	}
+	if o.consistent {
+		input.ConsistentRead = aws.Bool(true)
+	}
+	projection := r.projectedAttributes(o, key.Attributes()...)
+	if projection != nil {
+		expr, err := expression.NewBuilder().WithProjection(projectionBuilder(projection)).Build()
+		if err != nil {
+			return result, err
+		}
+		input.ProjectionExpression = expr.Projection()
+		input.ExpressionAttributeNames = expr.Names()
+	}
+	out, err := r.client.GetItem(ctx, input)
 	if err != nil {
 		return result, err
 	}
@@ -43,18 +101,162 @@ func (r *repositoryImpl[T]) Get(ctx context.Context, key Key) (T, error) {
 	if err != nil {
 		return result, err
 	}
+	if err := r.trackRead(result, projection); err != nil {
+		var zero T
+		return zero, err
+	}
+	if err := afterLoad(ctx, result); err != nil {
+		var zero T
+		return zero, err
+	}
 
 	return result, nil
 }
 
+// Query implements Repository.
+func (r *repositoryImpl[T]) Query(ctx context.Context, query *Query, opts ...ReadOption) (*QueryResult[T], error) {
+	o := newReadOptions(opts)
+	var index *Index
+	if query.index != "" {
+		idx, ok := r.indexes[query.index]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownIndex, query.index)
+		}
+		if query.partitionKey != idx.PartitionKey {
+			return nil, fmt.Errorf("index %q is partitioned by %q, not %q", idx.Name, idx.PartitionKey, query.partitionKey)
+		}
+		if query.sortKey != nil && query.sortKey.name != idx.SortKey {
+			return nil, fmt.Errorf("index %q is sorted by %q, not %q", idx.Name, idx.SortKey, query.sortKey.name)
+		}
+		index = &idx
+	} else if r.keySchema != nil {
+		if err := r.keySchema.validateQuery(query); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without its key attributes, the items could not be told apart, nor written back.
+	if o.projection != nil && r.keySchema == nil && len(r.modelKeyAttributes) == 0 {
+		return nil, fmt.Errorf("%w: the key attributes of %v are unknown, so they cannot be projected (WithKeySchema)",
+			ErrInvalidConfiguration, reflect.TypeOf((*T)(nil)).Elem())
+	}
+
+	var keyAttributes []string
+	if query.sortKey != nil {
+		keyAttributes = append(keyAttributes, query.sortKey.name)
+	}
+	if index != nil {
+		keyAttributes = append(keyAttributes, index.PartitionKey, index.SortKey)
+	}
+	projection := r.projectedAttributes(o, append(keyAttributes, query.partitionKey)...)
+	expr, err := query.expression(projection)
+	if err != nil {
+		return nil, err
+	}
+	startKey, err := query.exclusiveStartKey()
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 r.tableName,
+		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
+		ProjectionExpression:      expr.Projection(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ExclusiveStartKey:         startKey,
+		Limit:                     query.limit,
+	}
+	if query.descending {
+		input.ScanIndexForward = aws.Bool(false)
+	}
+	if o.consistent {
+		input.ConsistentRead = aws.Bool(true)
+	}
+	if index != nil {
+		input.IndexName = aws.String(index.Name)
+	}
+
+	out, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryResult[T]{
+		Items:            make([]T, 0, len(out.Items)),
+		LastEvaluatedKey: out.LastEvaluatedKey,
+	}
+	for _, item := range out.Items {
+		// Items read from an index may lack attributes that were not projected into it.
+		if index != nil && !index.projectsAll() {
+			if missing := missingAttributes(item, r.required); len(missing) > 0 {
+				return nil, &ProjectionError{Index: index.Name, Missing: missing}
+			}
+		}
+		// Convert each item to a model.
+		model, matched, err := r.loadModel(ctx, item, projection)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			if r.unmatched == ReportUnmatched {
+				result.Unmatched = append(result.Unmatched, item)
+			}
+			continue
+		}
+		result.Items = append(result.Items, model)
+	}
+
+	return result, nil
+}
+
+// loadModel converts an item that was read into a model, tracks it and calls its AfterLoad hook.
+// Returns false if the modeler does not support the item and the unmatched policy is not to fail.
+func (r *repositoryImpl[T]) loadModel(ctx context.Context, item map[string]types.AttributeValue, projection []string) (T, bool, error) {
+	var zero T
+	model, err := r.modeler(item)
+	if errors.Is(err, IncompatibleModelerError) && r.unmatched != FailUnmatched {
+		return zero, false, nil
+	}
+	if err != nil {
+		return zero, false, err
+	}
+	if err := r.trackRead(model, projection); err != nil {
+		return zero, false, err
+	}
+	if err := afterLoad(ctx, model); err != nil {
+		return zero, false, err
+	}
+	return model, true, nil
+}
+
+// trackRead snapshots a model that was read, if it opted into change tracking. The snapshot is the item the model
+// marshals into rather than the stored item, so that attributes the model does not hold are neither reported as
+// changes nor removed, and attributes it holds but that were not stored are not reported as changes either.
+func (r *repositoryImpl[T]) trackRead(model T, projection []string) error {
+	if _, ok := Model(model).(tracker); !ok {
+		return nil
+	}
+	putItem, err := r.constructPutItem(model)
+	if err != nil {
+		return err
+	}
+	trackLoaded(model, putItem.Item, projection)
+	return nil
+}
+
 // TransactSaveMany implements Repository.
 func (r *repositoryImpl[T]) Create(ctx context.Context, model T) error {
 
 	key := model.Key()
-	if key == nil || len(key) == 0 {
-		return errors.New("key is required")
+	if err := r.checkKey(key); err != nil {
+		return err
 	}
 
+	if err := before(ctx, operationCreate, model); err != nil {
+		return err
+	}
 	putItem, err := r.constructPutItem(model)
 	if err != nil {
 		return err
@@ -63,7 +265,7 @@ func (r *repositoryImpl[T]) Create(ctx context.Context, model T) error {
 	// In order to satisfy the "Create" operation, we need to ensure that the item does not already exist.
 	// We do this by constructing a condition expression that asserts that the item does not exist.
 	// We'll infer the proper expression from the model.Key()
-	expr, err := key.CondtionExpressionForCreate()
+	expr, err := key.ConditionExpressionForCreate()
 	if err != nil {
 		return err
 	}
@@ -71,102 +273,271 @@ func (r *repositoryImpl[T]) Create(ctx context.Context, model T) error {
 	putItem.ExpressionAttributeNames = expr.Names()
 	putItem.ExpressionAttributeValues = expr.Values()
 
-	puts := []dynamodb.PutItemInput{*putItem}
+	// Versioned models start at version 1.
+	versionAttr := r.versionAttribute(model)
+	if versionAttr != "" {
+		if _, err := bumpVersion(putItem.Item, versionAttr); err != nil {
+			return err
+		}
+	}
+
+	writes := []write{{model: model, primary: true, item: types.TransactWriteItem{Put: putItem}, conditionErr: ErrAlreadyExists}}
+	writes, err = r.appendPutsFromRelatedModels(ctx, operationCreate, writes, model)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(ctx, writes, func() {
+		if versionAttr != "" {
+			writeBackVersion(model, putItem.Item, versionAttr)
+		}
+		track(model, putItem.Item)
+	})
+}
 
-	puts = r.appendPutsFromRelatedModels(puts, model)
+// TransactSaveMany implements Repository.
+func (r *repositoryImpl[T]) Update(ctx context.Context, model T) error {
+	key := model.Key()
+	if err := r.checkKey(key); err != nil {
+		return err
+	}
 
-	// If there's only one put, we can use PutItem.
-	if len(puts) == 1 {
-		_, err = r.client.PutItem(ctx, putItem)
+	if err := before(ctx, operationUpdate, model); err != nil {
+		return err
+	}
+
+	// Tracked models that were loaded or saved before only need their changes written.
+	if t, ok := Model(model).(tracker); ok && t.snapshot() != nil {
+		return r.updateChanges(ctx, model, t)
+	}
+
+	putItem, err := r.constructPutItem(model)
+	if err != nil {
+		return err
+	}
+
+	// In order to satisfy the "Update" operation, we need to ensure that the item already exists.
+	// We'll infer the proper expression from the model.Key()
+	var expr *expression.Expression
+	conditionErr := ErrDoesNotExist
+	versionAttr := r.versionAttribute(model)
+	if versionAttr != "" {
+		// Versioned models also need to be at the version they were loaded with.
+		version, err := bumpVersion(putItem.Item, versionAttr)
 		if err != nil {
 			return err
 		}
-	} else {
-		// Otherwise, we'll use TransactWriteItems.
-		input := &dynamodb.TransactWriteItemsInput{
-			TransactItems: make([]types.TransactWriteItem, 0),
-		}
-		for _, put := range puts {
-			item := types.TransactWriteItem{
-				Put: &types.Put{
-					Item:                      put.Item,
-					TableName:                 put.TableName,
-					ConditionExpression:       put.ConditionExpression,
-					ExpressionAttributeNames:  put.ExpressionAttributeNames,
-					ExpressionAttributeValues: put.ExpressionAttributeValues,
-				},
-			}
-			input.TransactItems = append(input.TransactItems, item)
+		built, err := expression.NewBuilder().WithCondition(
+			expression.And(key.existsCondition(), versionCondition(versionAttr, version)),
+		).Build()
+		if err != nil {
+			return err
 		}
-		_, err := r.client.TransactWriteItems(ctx, input)
+		expr = &built
+		conditionErr = ErrVersionConflict
+	} else {
+		expr, err = key.ConditionExpressionForUpdate()
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	putItem.ConditionExpression = expr.Condition()
+	putItem.ExpressionAttributeNames = expr.Names()
+	putItem.ExpressionAttributeValues = expr.Values()
+
+	writes := []write{{model: model, primary: true, item: types.TransactWriteItem{Put: putItem}, conditionErr: conditionErr}}
+	writes, err = r.appendPutsFromRelatedModels(ctx, operationUpdate, writes, model)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(ctx, writes, func() {
+		if versionAttr != "" {
+			writeBackVersion(model, putItem.Item, versionAttr)
+		}
+		track(model, putItem.Item)
+	})
 }
 
-// TransactSaveMany implements Repository.
-func (r *repositoryImpl[T]) Update(ctx context.Context, model T) error {
+// Patch implements Repository.
+func (r *repositoryImpl[T]) Patch(ctx context.Context, key Key, update expression.UpdateBuilder) error {
+	if err := r.checkKey(key); err != nil {
+		return err
+	}
+
+	// Without a model, the version the item is at is unknown. We can still increment it.
+	if r.modelVersionAttr != "" {
+		update = update.Add(expression.Name(r.modelVersionAttr), expression.Value(1))
+	}
+
+	updateItem, err := r.constructUpdateItem(key, update)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(ctx, []write{{primary: true, item: updateItem, conditionErr: ErrDoesNotExist}}, nil)
+}
+
+// PatchModel implements Repository.
+func (r *repositoryImpl[T]) PatchModel(ctx context.Context, model T, update expression.UpdateBuilder) error {
 	key := model.Key()
-	if key == nil || len(key) == 0 {
-		return errors.New("key is required")
+	if err := r.checkKey(key); err != nil {
+		return err
 	}
 
-	putItem, err := r.constructPutItem(model)
+	if err := before(ctx, operationUpdate, model); err != nil {
+		return err
+	}
+
+	var conditions []expression.ConditionBuilder
+	var item map[string]types.AttributeValue
+	conditionErr := ErrDoesNotExist
+	versionAttr := r.versionAttribute(model)
+	if versionAttr != "" {
+		putItem, err := r.constructPutItem(model)
+		if err != nil {
+			return err
+		}
+		item = putItem.Item
+		version, err := bumpVersion(item, versionAttr)
+		if err != nil {
+			return err
+		}
+		update = update.Set(expression.Name(versionAttr), expression.Value(item[versionAttr]))
+		conditions = append(conditions, versionCondition(versionAttr, version))
+		conditionErr = ErrVersionConflict
+	}
+
+	updateItem, err := r.constructUpdateItem(key, update, conditions...)
 	if err != nil {
 		return err
 	}
 
-	// In order to satisfy the "Update" operation, we need to ensure that the item already exists.
-	// We'll infer the proper expression from the model.Key()
-	expr, err := key.ConditionExpressionForUpdate()
+	writes := []write{{model: model, primary: true, item: updateItem, conditionErr: conditionErr}}
+	writes, err = r.appendPutsFromRelatedModels(ctx, operationUpdate, writes, model)
 	if err != nil {
 		return err
 	}
+	return r.commit(ctx, writes, func() {
+		if versionAttr != "" {
+			writeBackVersion(model, item, versionAttr)
+		}
+	})
+}
 
-	putItem.ConditionExpression = expr.Condition()
-	putItem.ExpressionAttributeNames = expr.Names()
-	putItem.ExpressionAttributeValues = expr.Values()
+// Changes implements Repository.
+func (r *repositoryImpl[T]) Changes(model T) ([]Change, error) {
+	t, ok := Model(model).(tracker)
+	if !ok || t.snapshot() == nil {
+		return nil, ErrNotTracked
+	}
+	putItem, err := r.constructPutItem(model)
+	if err != nil {
+		return nil, err
+	}
+	return diffLoaded(t, putItem.Item)
+}
 
-	puts := []dynamodb.PutItemInput{*putItem}
-	puts = r.appendPutsFromRelatedModels(puts, model)
+// updateChanges writes the attributes of a tracked model that changed since it was snapshotted,
+// transactionally with its relations.
+func (r *repositoryImpl[T]) updateChanges(ctx context.Context, model T, t tracker) error {
+	key := model.Key()
+	putItem, err := r.constructPutItem(model)
+	if err != nil {
+		return err
+	}
+	item := putItem.Item
+	related, err := r.appendPutsFromRelatedModels(ctx, operationUpdate, nil, model)
+	if err != nil {
+		return err
+	}
 
-	// If there's only one put, we can use PutItem.
-	if len(puts) == 1 {
-		_, err = r.client.PutItem(ctx, putItem)
+	changes, err := diffLoaded(t, item)
+	if err != nil {
+		return err
+	}
+	update, ok := updateFromChanges(changes, key)
+	versionAttr := r.versionAttribute(model)
+
+	var primary write
+	switch {
+	case versionAttr != "" && (ok || len(related) > 0):
+		// Versioned models are bumped whenever anything is written, and need to be at the version they were loaded with.
+		version, err := bumpVersion(item, versionAttr)
 		if err != nil {
 			return err
 		}
-	} else {
-		// Otherwise, we'll use TransactWriteItems.
-		input := &dynamodb.TransactWriteItemsInput{
-			TransactItems: make([]types.TransactWriteItem, 0),
-		}
-		for _, put := range puts {
-			item := types.TransactWriteItem{
-				Put: &types.Put{
-					Item:                      put.Item,
-					TableName:                 put.TableName,
-					ConditionExpression:       put.ConditionExpression,
-					ExpressionAttributeNames:  put.ExpressionAttributeNames,
-					ExpressionAttributeValues: put.ExpressionAttributeValues,
-				},
-			}
-			input.TransactItems = append(input.TransactItems, item)
+		update = update.Set(expression.Name(versionAttr), expression.Value(item[versionAttr]))
+		updateItem, err := r.constructUpdateItem(key, update, versionCondition(versionAttr, version))
+		if err != nil {
+			return err
+		}
+		primary = write{model: model, primary: true, item: updateItem, conditionErr: ErrVersionConflict}
+	case ok:
+		updateItem, err := r.constructUpdateItem(key, update)
+		if err != nil {
+			return err
 		}
-		_, err := r.client.TransactWriteItems(ctx, input)
+		primary = write{model: model, primary: true, item: updateItem, conditionErr: ErrDoesNotExist}
+	case len(related) > 0:
+		// Nothing changed on the item itself, but its related models still need to be saved.
+		// We still assert that the item exists, as the Update operation would.
+		expr, err := key.ConditionExpressionForUpdate()
 		if err != nil {
 			return err
 		}
+		check := types.TransactWriteItem{
+			ConditionCheck: &types.ConditionCheck{
+				Key:                       key,
+				TableName:                 r.tableName,
+				ConditionExpression:       expr.Condition(),
+				ExpressionAttributeNames:  expr.Names(),
+				ExpressionAttributeValues: expr.Values(),
+			},
+		}
+		primary = write{model: model, primary: true, item: check, conditionErr: ErrDoesNotExist}
+	default:
+		// Nothing to write.
+		return nil
 	}
 
-	return nil
+	return r.commit(ctx, append([]write{primary}, related...), func() {
+		if versionAttr != "" {
+			writeBackVersion(model, item, versionAttr)
+		}
+		t.setSnapshot(item)
+	})
 }
 
-func (r *repositoryImpl[T]) constructPutItem(model Model) (*dynamodb.PutItemInput, error) {
-	input := &dynamodb.PutItemInput{}
+// constructUpdateItem compiles the update into an update of the item identified by key, with a condition expression
+// that asserts that the item exists, along with any extra conditions.
+func (r *repositoryImpl[T]) constructUpdateItem(key Key, update expression.UpdateBuilder, conditions ...expression.ConditionBuilder) (types.TransactWriteItem, error) {
+	condition := key.existsCondition()
+	if len(conditions) > 0 {
+		condition = expression.And(condition, conditions[0], conditions[1:]...)
+	}
+
+	// The update and the condition need to be built together, so that they share the same placeholders.
+	expr, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			Key:                       key,
+			TableName:                 r.tableName,
+			UpdateExpression:          expr.Update(),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	}, nil
+}
+
+// constructPutItem constructs a put of the model's item, using the model's own condition expression if any.
+func (r *repositoryImpl[T]) constructPutItem(model Model) (*types.Put, error) {
+	input := &types.Put{}
 	input.TableName = r.tableName
 	item, err := attributevalue.MarshalMap(model.Item())
 	if err != nil {
@@ -189,22 +560,160 @@ func (r *repositoryImpl[T]) constructPutItem(model Model) (*dynamodb.PutItemInpu
 	return input, nil
 }
 
-func (r *repositoryImpl[T]) appendPutsFromRelatedModels(puts []dynamodb.PutItemInput, model Model) []dynamodb.PutItemInput {
-	// Check if the model type supports related models.
-	related, ok := Model(model).(HasRelated)
-	if ok {
-		relatedModels, err := related.Related()
+// appendPutsFromRelatedModels appends the puts of the model's related models, if it has any, calling their hooks
+// for the operation first. Fails if the related models cannot be determined or marshalled, so that nothing is written.
+func (r *repositoryImpl[T]) appendPutsFromRelatedModels(ctx context.Context, op operation, writes []write, model Model) ([]write, error) {
+	err := r.walkRelated(model, func(rel Model) error {
+		if err := before(ctx, op, rel); err != nil {
+			return err
+		}
+		relPut, err := r.constructPutItem(rel)
 		if err != nil {
-			return puts
+			return err
 		}
-		// TODO: Bredth-first search for related models, if we want to go beyond 1 layer deep.
-		for _, rel := range relatedModels {
-			relPut, err := r.constructPutItem(rel)
-			if err != nil {
-				return puts
-			}
-			puts = append(puts, *relPut)
+		relPut.TableName = r.tableNameFor(rel)
+		writes = append(writes, write{model: rel, item: types.TransactWriteItem{Put: relPut}, conditionErr: ErrConditionFailed})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return writes, nil
+}
+
+// Delete implements Repository.
+func (r *repositoryImpl[T]) Delete(ctx context.Context, key Key) error {
+	if err := r.checkKey(key); err != nil {
+		return err
+	}
+
+	deleteItem, err := r.constructDeleteItemForKey(key)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(ctx, []write{{primary: true, item: deleteItem, conditionErr: ErrDoesNotExist}}, nil)
+}
+
+// DeleteModel implements Repository.
+func (r *repositoryImpl[T]) DeleteModel(ctx context.Context, model T) error {
+	key := model.Key()
+	if err := r.checkKey(key); err != nil {
+		return err
+	}
+
+	if err := before(ctx, operationDelete, model); err != nil {
+		return err
+	}
+
+	// Versioned models also need to be at the version they were loaded with.
+	var conditions []expression.ConditionBuilder
+	conditionErr := ErrDoesNotExist
+	if versionAttr := r.versionAttribute(model); versionAttr != "" {
+		putItem, err := r.constructPutItem(model)
+		if err != nil {
+			return err
 		}
+		version, err := itemVersion(putItem.Item, versionAttr)
+		if err != nil {
+			return err
+		}
+		conditions = append(conditions, versionCondition(versionAttr, version))
+		conditionErr = ErrVersionConflict
+	}
+	deleteItem, err := r.constructDeleteItemForKey(key, conditions...)
+	if err != nil {
+		return err
+	}
+
+	writes := []write{{model: model, primary: true, item: deleteItem, conditionErr: conditionErr}}
+	writes, err = r.appendDeletesFromRelatedModels(ctx, writes, model)
+	if err != nil {
+		return err
+	}
+
+	return r.commit(ctx, writes, nil)
+}
+
+// ConditionCheck implements Repository.
+func (r *repositoryImpl[T]) ConditionCheck(ctx context.Context, key Key, condition expression.ConditionBuilder) error {
+	if err := r.checkKey(key); err != nil {
+		return err
+	}
+
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+	check := types.TransactWriteItem{
+		ConditionCheck: &types.ConditionCheck{
+			Key:                       key,
+			TableName:                 r.tableName,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	}
+
+	return r.commit(ctx, []write{{primary: true, item: check, conditionErr: ErrConditionFailed}}, nil)
+}
+
+// constructDeleteItemForKey constructs a delete of the item identified by key, with a condition expression
+// that asserts that the item exists, along with any extra conditions.
+func (r *repositoryImpl[T]) constructDeleteItemForKey(key Key, conditions ...expression.ConditionBuilder) (types.TransactWriteItem, error) {
+	condition := key.existsCondition()
+	if len(conditions) > 0 {
+		condition = expression.And(condition, conditions[0], conditions[1:]...)
+	}
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			Key:                       key,
+			TableName:                 r.tableName,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		},
+	}, nil
+}
+
+// constructDeleteItem constructs a delete of a related model, using the model's own condition expression if any.
+func (r *repositoryImpl[T]) constructDeleteItem(model Model) (types.TransactWriteItem, error) {
+	key := model.Key()
+	if key == nil || len(key) == 0 {
+		return types.TransactWriteItem{}, errors.New("key is required")
+	}
+	input := &types.Delete{
+		Key:       key,
+		TableName: r.tableNameFor(model),
+	}
+	if expr := model.ConditionExpression(); expr != nil {
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+	return types.TransactWriteItem{Delete: input}, nil
+}
+
+// appendDeletesFromRelatedModels appends the deletes of the model's related models, if it has any,
+// calling their BeforeDelete hooks first.
+func (r *repositoryImpl[T]) appendDeletesFromRelatedModels(ctx context.Context, writes []write, model Model) ([]write, error) {
+	err := r.walkRelated(model, func(rel Model) error {
+		if err := before(ctx, operationDelete, rel); err != nil {
+			return err
+		}
+		relDelete, err := r.constructDeleteItem(rel)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, write{model: rel, item: relDelete, conditionErr: ErrConditionFailed})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return puts
+	return writes, nil
 }
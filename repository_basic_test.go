@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
 	"github.com/bezhermoso/dynamorm/internal/examples"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -34,6 +35,65 @@ func TestBuilder(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestBuilder_Invalid(t *testing.T) {
+	_, err := dynamorm.NewBuilder[*examples.BasicModel]().
+		WithIndex(dynamorm.Index{Name: "ByAge"}).
+		WithBatchConcurrency(0).
+		Build()
+
+	// Every problem is reported at once.
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+	assert.EqualError(t, err, "invalid repository configuration: "+
+		"a client is required (WithClient); "+
+		"a table name is required (WithTableName); "+
+		"index \"ByAge\" requires a partition key (WithIndex); "+
+		"the batch concurrency must be at least 1 (WithBatchConcurrency)")
+
+	// Models that are not pointers to structs cannot use the default modeler.
+	client, _ := newStubbedClient()
+	_, err = dynamorm.NewBuilder[dynamorm.Model]().
+		WithClient(client).
+		WithTableName("people").
+		Build()
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+}
+
+func TestBuilder_BuildAndVerify(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(
+		dynamormtest.Table{
+			Name:         "teams",
+			PartitionKey: "PK",
+			SortKey:      "SK",
+			Indexes:      []dynamorm.Index{{Name: "ByRole", PartitionKey: "Role", SortKey: "SK"}},
+		},
+		dynamormtest.Table{Name: "profiles", PartitionKey: "PK"},
+	)
+
+	_, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithIndex(dynamorm.Index{Name: "ByRole", PartitionKey: "Role", SortKey: "SK"}).
+		BuildAndVerify(ctx)
+	assert.NoError(t, err)
+
+	_, err = dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("members").
+		BuildAndVerify(ctx)
+	assert.ErrorIs(t, err, dynamorm.ErrTableNotFound)
+
+	_, err = dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		WithIndex(dynamorm.Index{Name: "ByRole", PartitionKey: "Role"}).
+		BuildAndVerify(ctx)
+	assert.ErrorIs(t, err, dynamorm.ErrSchemaMismatch)
+	assert.EqualError(t, err, "table schema mismatch: table profiles: "+
+		"the key of the model is (PK, SK), but the key of the table is (PK); "+
+		"index \"ByRole\" does not exist")
+}
+
 func TestGet(t *testing.T) {
 	client, stubber := newStubbedClient()
 	stubber.Add(
@@ -72,3 +132,166 @@ func TestGet(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, key, model.Key())
 }
+
+func TestDelete(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "DeleteItem",
+			Input: &dynamodb.DeleteItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "ABC"},
+				},
+				TableName:           aws.String("people"),
+				ConditionExpression: aws.String("attribute_exists (#0)"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+				},
+			},
+			Output: &dynamodb.DeleteItemOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.BasicModel]().
+		WithClient(client).
+		WithTableName("people").
+		WithModeler(examples.NewBasicModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Delete(context.Background(), dynamorm.Key{
+		"PK": dynamorm.KeyValue("ABC"),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+// fakeGetClient stands in for a DynamoDB client, serving GetItem from memory.
+// The other operations are not implemented, and panic if called.
+type fakeGetClient struct {
+	dynamorm.DynamoDBAPI
+	items map[string]map[string]types.AttributeValue
+	calls int
+}
+
+func (c *fakeGetClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	c.calls++
+	return &dynamodb.GetItemOutput{Item: c.items[dynamorm.Key(params.Key).String()]}, nil
+}
+
+func TestGet_CustomClient(t *testing.T) {
+	key := dynamorm.Key{"PK": dynamorm.KeyValue("ABC"), "SK": dynamorm.KeyValue("123")}
+	client := &fakeGetClient{
+		items: map[string]map[string]types.AttributeValue{
+			key.String(): {
+				"PK":   &types.AttributeValueMemberS{Value: "ABC"},
+				"SK":   &types.AttributeValueMemberS{Value: "123"},
+				"Name": &types.AttributeValueMemberS{Value: "John Appleseed"},
+			},
+		},
+	}
+
+	repo, err := dynamorm.NewBuilder[*examples.BasicModel]().
+		WithClient(client).
+		WithTableName("people").
+		WithModeler(examples.NewBasicModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	model, err := repo.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.Equal(t, key, model.Key())
+	assert.Equal(t, 1, client.calls)
+
+	_, err = repo.Get(context.Background(), dynamorm.Key{"PK": dynamorm.KeyValue("XYZ"), "SK": dynamorm.KeyValue("123")})
+	assert.ErrorIs(t, err, dynamorm.ErrNotFound)
+}
+
+func TestKey_ConditionExpressions(t *testing.T) {
+	key := dynamorm.Key{
+		"SK":     dynamorm.KeyValue("123"),
+		"PK":     dynamorm.KeyValue("ABC"),
+		"Tenant": dynamorm.KeyValue("T1"),
+	}
+	assert.Equal(t, []string{"PK", "SK", "Tenant"}, key.Attributes())
+
+	// Every attribute is asserted, with placeholders assigned in sorted order.
+	for i := 0; i < 10; i++ {
+		expr, err := key.ConditionExpressionForCreate()
+		assert.NoError(t, err)
+		assert.Equal(t, "(attribute_not_exists (#0)) AND (attribute_not_exists (#1)) AND (attribute_not_exists (#2))", *expr.Condition())
+		assert.Equal(t, map[string]string{"#0": "PK", "#1": "SK", "#2": "Tenant"}, expr.Names())
+
+		expr, err = key.ConditionExpressionForUpdate()
+		assert.NoError(t, err)
+		assert.Equal(t, "(attribute_exists (#0)) AND (attribute_exists (#1)) AND (attribute_exists (#2))", *expr.Condition())
+		assert.Equal(t, map[string]string{"#0": "PK", "#1": "SK", "#2": "Tenant"}, expr.Names())
+	}
+
+	_, err := dynamorm.Key{}.ConditionExpressionForCreate()
+	assert.Error(t, err)
+}
+
+func TestKeySchema(t *testing.T) {
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "42"}, dynamorm.KeyNumber(42))
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "18446744073709551615"}, dynamorm.KeyNumber(uint64(18446744073709551615)))
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "1.5"}, dynamorm.KeyNumber(float32(1.5)))
+	assert.Equal(t, &types.AttributeValueMemberB{Value: []byte{1, 2}}, dynamorm.KeyBinary([]byte{1, 2}))
+
+	ctx := context.Background()
+	table := dynamormtest.Table{
+		Name:             "events",
+		PartitionKey:     "Stream",
+		SortKey:          "Sequence",
+		PartitionKeyType: types.ScalarAttributeTypeS,
+		SortKeyType:      types.ScalarAttributeTypeN,
+	}
+	client := dynamormtest.NewClient(table)
+	repo, err := dynamorm.NewBuilder[*examples.EventModel]().
+		WithClient(client).
+		WithTableName("events").
+		WithKeySchema(examples.EventKeySchema).
+		BuildAndVerify(ctx)
+	assert.NoError(t, err)
+
+	// Items are sorted by their numeric sort key.
+	for _, seq := range []int64{10, 9, 100} {
+		assert.NoError(t, repo.Create(ctx, &examples.EventModel{Stream: "S1", Sequence: seq}))
+	}
+	page, err := repo.Query(ctx, dynamorm.NewQuery("Stream", dynamorm.KeyValue("S1")).
+		WithSortKey(dynamorm.SortKeyGreaterThan("Sequence", dynamorm.KeyNumber(9))))
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, int64(10), page.Items[0].Sequence)
+	assert.Equal(t, int64(100), page.Items[1].Sequence)
+
+	// Keys that do not conform to the schema fail before any request is sent.
+	_, err = repo.Get(ctx, dynamorm.Key{"Stream": dynamorm.KeyValue("S1"), "Sequence": dynamorm.KeyValue("10")})
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidKey)
+	assert.EqualError(t, err, `invalid key: {"Sequence"="S:10", "Stream"="S:S1"}: attribute "Sequence" must be of type N, not S`)
+	err = repo.Delete(ctx, dynamorm.Key{"Stream": dynamorm.KeyValue("S1"), "Tenant": dynamorm.KeyValue("T1")})
+	assert.EqualError(t, err, `invalid key: {"Stream"="S:S1", "Tenant"="S:T1"}: attribute "Sequence" is missing; attribute "Tenant" is not part of the key`)
+	_, err = repo.Query(ctx, dynamorm.NewQuery("Stream", dynamorm.KeyNumber(1)))
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidKey)
+	assert.Len(t, client.Items("events"), 3)
+
+	// The schema is checked against the table.
+	_, err = dynamorm.NewBuilder[*examples.EventModel]().
+		WithClient(client).
+		WithTableName("events").
+		WithKeySchema(dynamorm.KeySchema{
+			PartitionKey: dynamorm.KeyAttribute{Name: "Stream", Type: types.ScalarAttributeTypeS},
+			SortKey:      dynamorm.KeyAttribute{Name: "Sequence", Type: types.ScalarAttributeTypeS},
+		}).
+		BuildAndVerify(ctx)
+	assert.EqualError(t, err, `table schema mismatch: table events: key attribute "Sequence" is declared of type S, but its type is N`)
+
+	_, err = dynamorm.NewBuilder[*examples.EventModel]().
+		WithClient(client).
+		WithTableName("events").
+		WithKeySchema(dynamorm.KeySchema{SortKey: dynamorm.KeyAttribute{Name: "Sequence", Type: "X"}}).
+		Build()
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+	assert.EqualError(t, err, `invalid repository configuration: the key schema requires a partition key (WithKeySchema); `+
+		`key attribute "Sequence" must be of type S, N or B (WithKeySchema)`)
+}
@@ -0,0 +1,339 @@
+package dynamorm_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+var fastRetries = dynamorm.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    time.Millisecond,
+}
+
+func teamKey(team, member string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: team},
+		"SK": &types.AttributeValueMemberS{Value: member},
+	}
+}
+
+func teamItem(team, member, role string) map[string]types.AttributeValue {
+	item := teamKey(team, member)
+	item["Role"] = &types.AttributeValueMemberS{Value: role}
+	return item
+}
+
+func TestBatchGet(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves the first attempt: one key is left unprocessed.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "BatchGetItem",
+			Input: &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					"teams": {
+						Keys: []map[string]types.AttributeValue{
+							teamKey("T1", "M1"),
+							teamKey("T1", "M2"),
+							teamKey("T1", "M3"),
+						},
+					},
+				},
+			},
+			Output: &dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"teams": {teamItem("T1", "M2", "member")},
+				},
+				UnprocessedKeys: map[string]types.KeysAndAttributes{
+					"teams": {Keys: []map[string]types.AttributeValue{teamKey("T1", "M1")}},
+				},
+			},
+		},
+	)
+
+	// Serves the retry of the unprocessed key.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "BatchGetItem",
+			Input: &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					"teams": {Keys: []map[string]types.AttributeValue{teamKey("T1", "M1")}},
+				},
+			},
+			Output: &dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"teams": {teamItem("T1", "M1", "admin")},
+				},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithRetryPolicy(fastRetries).
+		Build()
+	assert.Nil(t, err)
+
+	result, err := repo.BatchGet(context.Background(), []dynamorm.Key{
+		teamKey("T1", "M1"),
+		teamKey("T1", "M2"),
+		teamKey("T1", "M3"),
+		// Duplicates are only requested once.
+		teamKey("T1", "M1"),
+	})
+	assert.NoError(t, err)
+
+	// Models are in the order of the keys, and missing keys are reported separately.
+	assert.Equal(t, []*examples.TaggedModel{
+		{Team: "T1", Member: "M1", Role: "admin"},
+		{Team: "T1", Member: "M2", Role: "member"},
+		{Team: "T1", Member: "M1", Role: "admin"},
+	}, result.Items)
+	assert.Equal(t, []dynamorm.Key{teamKey("T1", "M3")}, result.Missing)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestBatchGet_Unprocessed(t *testing.T) {
+	client, stubber := newStubbedClient()
+	for i := 0; i < fastRetries.MaxAttempts; i++ {
+		stubber.Add(
+			testtools.Stub{
+				OperationName: "BatchGetItem",
+				Input: &dynamodb.BatchGetItemInput{
+					RequestItems: map[string]types.KeysAndAttributes{
+						"teams": {Keys: []map[string]types.AttributeValue{teamKey("T1", "M1")}},
+					},
+				},
+				Output: &dynamodb.BatchGetItemOutput{
+					UnprocessedKeys: map[string]types.KeysAndAttributes{
+						"teams": {Keys: []map[string]types.AttributeValue{teamKey("T1", "M1")}},
+					},
+				},
+			},
+		)
+	}
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithRetryPolicy(fastRetries).
+		Build()
+	assert.Nil(t, err)
+
+	_, err = repo.BatchGet(context.Background(), []dynamorm.Key{teamKey("T1", "M1")})
+	assert.ErrorIs(t, err, dynamorm.ErrUnprocessed)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestBatchPut(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// 26 distinct members are written in two requests of 25 and 1.
+	var models []*examples.TaggedModel
+	var first []types.WriteRequest
+	for i := 0; i < dynamorm.MaxBatchWriteItems+1; i++ {
+		member := fmt.Sprintf("M%02d", i)
+		models = append(models, &examples.TaggedModel{Team: "T1", Member: member, Role: "member"})
+		if i < dynamorm.MaxBatchWriteItems {
+			first = append(first, types.WriteRequest{PutRequest: &types.PutRequest{Item: teamItem("T1", member, "member")}})
+		}
+	}
+	// Duplicates are only written once, with the last value.
+	models = append(models, &examples.TaggedModel{Team: "T1", Member: "M25", Role: "admin"})
+	last := types.WriteRequest{PutRequest: &types.PutRequest{Item: teamItem("T1", "M25", "admin")}}
+
+	// The first request leaves one item unprocessed.
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": first},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: map[string][]types.WriteRequest{"teams": first[3:4]},
+		},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": first[3:4]},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": {last}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{},
+	})
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithRetryPolicy(fastRetries).
+		// The stubber serves requests in order.
+		WithBatchConcurrency(1).
+		Build()
+	assert.Nil(t, err)
+
+	report, err := repo.BatchPut(context.Background(), models)
+	assert.NoError(t, err)
+	assert.Len(t, report.Results, len(models))
+	assert.Empty(t, report.Failed())
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestBatchDelete_Unprocessed(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	deleteM1 := types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: teamKey("T1", "M1")}}
+	deleteM2 := types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: teamKey("T1", "M2")}}
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": {deleteM1, deleteM2}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: map[string][]types.WriteRequest{"teams": {deleteM2}},
+		},
+	})
+	for i := 1; i < fastRetries.MaxAttempts; i++ {
+		stubber.Add(testtools.Stub{
+			OperationName: "BatchWriteItem",
+			Input: &dynamodb.BatchWriteItemInput{
+				RequestItems: map[string][]types.WriteRequest{"teams": {deleteM2}},
+			},
+			Output: &dynamodb.BatchWriteItemOutput{
+				UnprocessedItems: map[string][]types.WriteRequest{"teams": {deleteM2}},
+			},
+		})
+	}
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithRetryPolicy(fastRetries).
+		Build()
+	assert.Nil(t, err)
+
+	report, err := repo.BatchDelete(context.Background(), []dynamorm.Key{teamKey("T1", "M1"), teamKey("T1", "M2")})
+	assert.ErrorIs(t, err, dynamorm.ErrUnprocessed)
+
+	// Only the item that was never processed is reported as failed.
+	assert.NoError(t, report.Results[0].Err)
+	assert.ErrorIs(t, report.Results[1].Err, dynamorm.ErrUnprocessed)
+	assert.Equal(t, []dynamorm.BatchWriteResult{report.Results[1]}, report.Failed())
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestBatchDelete_NoRetryDelay(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	deleteM1 := types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: teamKey("T1", "M1")}}
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": {deleteM1}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: map[string][]types.WriteRequest{"teams": {deleteM1}},
+		},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"teams": {deleteM1}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{},
+	})
+
+	// A zero BaseDelay retries right away, rather than after MaxDelay.
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithRetryPolicy(dynamorm.RetryPolicy{MaxAttempts: 2, MaxDelay: time.Hour}).
+		Build()
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := repo.BatchDelete(ctx, []dynamorm.Key{teamKey("T1", "M1")})
+	assert.NoError(t, err)
+	assert.Empty(t, report.Failed())
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestBatch_NumberKeys(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Numbers are returned in normalized form, whichever form they were requested in.
+	requested := func(seq string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"Stream":   &types.AttributeValueMemberS{Value: "S1"},
+			"Sequence": &types.AttributeValueMemberN{Value: seq},
+		}
+	}
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchGetItem",
+		Input: &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{
+				"events": {Keys: []map[string]types.AttributeValue{requested("01"), requested("2.0")}},
+			},
+		},
+		Output: &dynamodb.BatchGetItemOutput{
+			Responses: map[string][]map[string]types.AttributeValue{
+				"events": {requested("1"), requested("2")},
+			},
+		},
+	})
+	deleteRequest := func(seq string) types.WriteRequest {
+		return types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: requested(seq)}}
+	}
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"events": {deleteRequest("2.0")}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{
+			UnprocessedItems: map[string][]types.WriteRequest{"events": {deleteRequest("2")}},
+		},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchWriteItem",
+		Input: &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"events": {deleteRequest("2")}},
+		},
+		Output: &dynamodb.BatchWriteItemOutput{},
+	})
+
+	repo, err := dynamorm.NewBuilder[*examples.EventModel]().
+		WithClient(client).
+		WithTableName("events").
+		WithRetryPolicy(fastRetries).
+		Build()
+	assert.Nil(t, err)
+
+	result, err := repo.BatchGet(context.Background(), []dynamorm.Key{requested("01"), requested("2.0")})
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, 2)
+	assert.Empty(t, result.Missing)
+
+	// The unprocessed item is retried rather than taken as written.
+	report, err := repo.BatchDelete(context.Background(), []dynamorm.Key{requested("2.0")})
+	assert.NoError(t, err)
+	assert.Empty(t, report.Failed())
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
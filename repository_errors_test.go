@@ -0,0 +1,61 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate_AlreadyExists(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Error: &testtools.StubError{
+				Err: &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Create(context.Background(), &examples.TaggedModel{Team: "T1", Member: "M1"})
+	assert.ErrorIs(t, err, dynamorm.ErrAlreadyExists)
+
+	// The original error remains available.
+	var ccf *types.ConditionalCheckFailedException
+	assert.ErrorAs(t, err, &ccf)
+}
+
+func TestDelete_DoesNotExist(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "DeleteItem",
+			Error: &testtools.StubError{
+				Err: &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Delete(context.Background(), dynamorm.Key{"PK": dynamorm.KeyValue("T1")})
+	assert.ErrorIs(t, err, dynamorm.ErrDoesNotExist)
+	assert.NotErrorIs(t, err, dynamorm.ErrAlreadyExists)
+}
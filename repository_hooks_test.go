@@ -0,0 +1,97 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate_Hooks(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// The slug is derived by BeforeCreate, before the item is constructed.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Input: &dynamodb.PutItemInput{
+				TableName: aws.String("notes"),
+				Item: map[string]types.AttributeValue{
+					"PK":    &types.AttributeValueMemberS{Value: "N1"},
+					"Title": &types.AttributeValueMemberS{Value: "Hello  World"},
+					"Slug":  &types.AttributeValueMemberS{Value: "hello-world"},
+				},
+				ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+				ExpressionAttributeNames: map[string]string{"#0": "PK"},
+			},
+			Output: &dynamodb.PutItemOutput{},
+		},
+	)
+	// Serves the second Create, which fails.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Error: &testtools.StubError{
+				Err: &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.NoteModel]().
+		WithClient(client).
+		WithTableName("notes").
+		Build()
+	assert.Nil(t, err)
+
+	note := &examples.NoteModel{ID: "N1", Title: "Hello  World"}
+	assert.NoError(t, repo.Create(context.Background(), note))
+	assert.True(t, note.Persisted)
+
+	// AfterSave is not called when the write fails.
+	note = &examples.NoteModel{ID: "N1", Title: "Hello again"}
+	assert.ErrorIs(t, repo.Create(context.Background(), note), dynamorm.ErrAlreadyExists)
+	assert.False(t, note.Persisted)
+
+	// Errors of BeforeCreate abort the operation: there is no stub left to serve it.
+	note = &examples.NoteModel{ID: "N2"}
+	assert.EqualError(t, repo.Create(context.Background(), note), "title is required")
+	assert.False(t, note.Persisted)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestGet_AfterLoad(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "GetItem",
+			Input: &dynamodb.GetItemInput{
+				TableName: aws.String("notes"),
+				Key:       map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "N1"}},
+			},
+			Output: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"PK":    &types.AttributeValueMemberS{Value: "N1"},
+					"Title": &types.AttributeValueMemberS{Value: "Hello"},
+					"Slug":  &types.AttributeValueMemberS{Value: "hello"},
+				},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.NoteModel]().
+		WithClient(client).
+		WithTableName("notes").
+		Build()
+	assert.Nil(t, err)
+
+	note, err := repo.Get(context.Background(), dynamorm.Key{"PK": dynamorm.KeyValue("N1")})
+	assert.NoError(t, err)
+	assert.True(t, note.Persisted)
+}
@@ -0,0 +1,85 @@
+package dynamorm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyToken(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	// A retried Create is not applied twice, so it does not fail.
+	tokenCtx := dynamorm.WithIdempotencyToken(ctx, "create-M1")
+	assert.NoError(t, repo.Create(tokenCtx, &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}))
+	assert.NoError(t, repo.Create(tokenCtx, &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}))
+	assert.ErrorIs(t, repo.Create(ctx, &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}), dynamorm.ErrAlreadyExists)
+
+	// Reusing the token for another write fails.
+	err = repo.Create(tokenCtx, &examples.TaggedModel{Team: "T1", Member: "M2"})
+	assert.ErrorIs(t, err, dynamorm.ErrIdempotentParameterMismatch)
+	var mismatch *types.IdempotentParameterMismatchException
+	assert.ErrorAs(t, err, &mismatch)
+	assert.Len(t, client.Items("teams"), 1)
+
+	// Conditions still fail as they would without a token.
+	err = repo.Create(dynamorm.WithIdempotencyToken(ctx, "create-M1-again"), &examples.TaggedModel{Team: "T1", Member: "M1"})
+	assert.ErrorIs(t, err, dynamorm.ErrAlreadyExists)
+
+	// Saves are retried the same way, whichever attempt succeeded.
+	saveCtx := dynamorm.WithIdempotencyToken(ctx, "save-M1")
+	for i := 0; i < 2; i++ {
+		outcome, err := repo.Save(saveCtx, &examples.TaggedModel{Team: "T1", Member: "M1", Role: "owner"})
+		assert.NoError(t, err)
+		assert.Equal(t, dynamorm.SaveReplaced, outcome)
+	}
+
+	_, err = repo.Save(dynamorm.WithIdempotencyToken(ctx, strings.Repeat("x", 37)), &examples.TaggedModel{Team: "T1", Member: "M1"})
+	assert.EqualError(t, err, "idempotency token is longer than 36 characters")
+}
+
+func TestIdempotencyToken_Provider(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	type requestIDKey struct{}
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithIdempotencyTokenProvider(func(ctx context.Context) string {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return id
+		}).
+		Build()
+	assert.Nil(t, err)
+
+	invocationCtx := context.WithValue(ctx, requestIDKey{}, "request-1")
+	assert.NoError(t, repo.Create(invocationCtx, &examples.TaggedModel{Team: "T1", Member: "M1"}))
+	assert.NoError(t, repo.Create(invocationCtx, &examples.TaggedModel{Team: "T1", Member: "M1"}))
+
+	// Tokens bound to the context take precedence.
+	err = repo.Create(dynamorm.WithIdempotencyToken(invocationCtx, "explicit"), &examples.TaggedModel{Team: "T1", Member: "M1"})
+	assert.ErrorIs(t, err, dynamorm.ErrAlreadyExists)
+
+	// Units of work are committed with their own token.
+	for i := 0; i < 2; i++ {
+		uow := dynamorm.NewUnitOfWork(client).WithIdempotencyToken("uow-1")
+		uowCtx := dynamorm.WithUnitOfWork(ctx, uow)
+		assert.NoError(t, repo.Create(uowCtx, &examples.TaggedModel{Team: "T1", Member: "M2"}))
+		assert.NoError(t, repo.Create(uowCtx, &examples.TaggedModel{Team: "T1", Member: "M3"}))
+		assert.NoError(t, uow.Commit(ctx))
+	}
+	assert.Len(t, client.Items("teams"), 3)
+}
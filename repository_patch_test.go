@@ -0,0 +1,64 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatch(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "UpdateItem",
+			Input: &dynamodb.UpdateItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "ABC"},
+					"SK": &types.AttributeValueMemberS{Value: "123"},
+				},
+				TableName:           aws.String("people"),
+				ConditionExpression: aws.String("(attribute_exists (#0)) AND (attribute_exists (#1))"),
+				UpdateExpression:    aws.String("ADD #2 :0\nREMOVE #3\nSET #4 = :1\n"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+					"#1": "SK",
+					"#2": "Age",
+					"#3": "Hobbies",
+					"#4": "Name",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberN{Value: "1"},
+					":1": &types.AttributeValueMemberS{Value: "John Appleseed, Sr."},
+				},
+			},
+			Output: &dynamodb.UpdateItemOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.BasicModel]().
+		WithClient(client).
+		WithTableName("people").
+		WithModeler(examples.NewBasicModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	update := expression.Set(expression.Name("Name"), expression.Value("John Appleseed, Sr.")).
+		Add(expression.Name("Age"), expression.Value(1)).
+		Remove(expression.Name("Hobbies"))
+
+	err = repo.Patch(context.Background(), dynamorm.Key{
+		"PK": dynamorm.KeyValue("ABC"),
+		"SK": dynamorm.KeyValue("123"),
+	}, update)
+	assert.NoError(t, err)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
@@ -0,0 +1,80 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolymorphicModeler(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "accounts", PartitionKey: "PK", SortKey: "SK"})
+	newRepo := func(policy dynamorm.UnmatchedPolicy) dynamorm.Repository[dynamorm.Model] {
+		repo, err := dynamorm.NewBuilder[dynamorm.Model]().
+			WithClient(client).
+			WithTableName("accounts").
+			WithModeler(examples.NewAccountModeler()).
+			WithUnmatchedItems(policy).
+			Build()
+		assert.Nil(t, err)
+		return repo
+	}
+	repo := newRepo(dynamorm.FailUnmatched)
+
+	for _, m := range []dynamorm.Model{
+		examples.NewAccount("A1", "Acme"),
+		examples.NewMembership("A1", "U2", "member"),
+		examples.NewMembership("A1", "U1", "owner"),
+	} {
+		assert.NoError(t, repo.Create(ctx, m))
+	}
+	// An item of a kind that the modeler does not know about.
+	invitations, err := dynamorm.NewBuilder[*examples.TaggedModel]().WithClient(client).WithTableName("accounts").Build()
+	assert.Nil(t, err)
+	assert.NoError(t, invitations.Create(ctx, &examples.TaggedModel{Team: "A1", Member: "INVITE#U3"}))
+
+	query := dynamorm.NewQuery("PK", dynamorm.KeyValue("A1"))
+	_, err = repo.Query(ctx, query)
+	assert.ErrorIs(t, err, dynamorm.IncompatibleModelerError)
+	assert.EqualError(t, err, `modeler does not support this item: no string "Type" attribute`)
+
+	page, err := newRepo(dynamorm.SkipUnmatched).Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, []dynamorm.Model{
+		examples.NewAccount("A1", "Acme"),
+		examples.NewMembership("A1", "U1", "owner"),
+		examples.NewMembership("A1", "U2", "member"),
+	}, page.Items)
+	assert.Empty(t, page.Unmatched)
+
+	page, err = newRepo(dynamorm.ReportUnmatched).Query(ctx, query)
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 3)
+	assert.Len(t, page.Unmatched, 1)
+	assert.Equal(t, dynamorm.KeyValue("INVITE#U3"), page.Unmatched[0]["SK"])
+
+	batch, err := newRepo(dynamorm.ReportUnmatched).BatchGet(ctx, []dynamorm.Key{
+		{"PK": dynamorm.KeyValue("A1"), "SK": dynamorm.KeyValue("INVITE#U3")},
+		{"PK": dynamorm.KeyValue("A1"), "SK": dynamorm.KeyValue("ACCOUNT")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []dynamorm.Model{examples.NewAccount("A1", "Acme")}, batch.Items)
+	assert.Len(t, batch.Unmatched, 1)
+
+	// Get has nothing else to return.
+	_, err = newRepo(dynamorm.SkipUnmatched).Get(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("A1"), "SK": dynamorm.KeyValue("INVITE#U3")})
+	assert.ErrorIs(t, err, dynamorm.IncompatibleModelerError)
+
+	_, err = dynamorm.NewBuilder[dynamorm.Model]().
+		WithClient(client).
+		WithTableName("accounts").
+		WithModeler(examples.NewAccountModeler()).
+		WithUnmatchedItems(dynamorm.UnmatchedPolicy(7)).
+		Build()
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+}
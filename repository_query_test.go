@@ -0,0 +1,159 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	client, stubber := newStubbedClient()
+	lastKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "ABC"},
+		"SK": &types.AttributeValueMemberS{Value: "124"},
+	}
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "Query",
+			Input: &dynamodb.QueryInput{
+				TableName:              aws.String("people"),
+				KeyConditionExpression: aws.String("(#1 = :1) AND (begins_with (#2, :2))"),
+				FilterExpression:       aws.String("#0 > :0"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "Age",
+					"#1": "PK",
+					"#2": "SK",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberN{Value: "18"},
+					":1": &types.AttributeValueMemberS{Value: "ABC"},
+					":2": &types.AttributeValueMemberS{Value: "12"},
+				},
+				Limit:            aws.Int32(2),
+				ScanIndexForward: aws.Bool(false),
+			},
+			Output: &dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					{
+						"PK":   &types.AttributeValueMemberS{Value: "ABC"},
+						"SK":   &types.AttributeValueMemberS{Value: "123"},
+						"Name": &types.AttributeValueMemberS{Value: "John Appleseed"},
+						"Age":  &types.AttributeValueMemberN{Value: "30"},
+					},
+					{
+						"PK":   &types.AttributeValueMemberS{Value: "ABC"},
+						"SK":   &types.AttributeValueMemberS{Value: "124"},
+						"Name": &types.AttributeValueMemberS{Value: "Jane Appleseed"},
+						"Age":  &types.AttributeValueMemberN{Value: "28"},
+					},
+				},
+				LastEvaluatedKey: lastKey,
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.BasicModel]().
+		WithClient(client).
+		WithTableName("people").
+		WithModeler(examples.NewBasicModeler()).
+		Build()
+	assert.Nil(t, err)
+
+	query := dynamorm.NewQuery("PK", dynamorm.KeyValue("ABC")).
+		WithSortKey(dynamorm.SortKeyBeginsWith("SK", "12")).
+		WithFilter(expression.Name("Age").GreaterThan(expression.Value(18))).
+		WithLimit(2).
+		Descending()
+
+	result, err := repo.Query(context.Background(), query)
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, 2)
+	assert.Equal(t, dynamorm.KeyValue("124"), result.Items[1].Key()["SK"])
+	assert.True(t, result.HasMore())
+
+	cursor, err := result.Cursor()
+	assert.NoError(t, err)
+	decoded, err := dynamorm.DecodeCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.Key(lastKey), decoded)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, err := dynamorm.DecodeCursor("not a cursor!")
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+}
+
+func newIndexedRepo(client *dynamodb.Client) (dynamorm.Repository[*examples.BasicModel], error) {
+	return dynamorm.NewBuilder[*examples.BasicModel]().
+		WithClient(client).
+		WithTableName("people").
+		WithModeler(examples.NewBasicModeler()).
+		WithIndex(dynamorm.Index{
+			Name:         "ByName",
+			PartitionKey: "Name",
+			Projection:   types.ProjectionTypeKeysOnly,
+		}).
+		WithRequiredAttributes("PK", "SK", "Age").
+		Build()
+}
+
+func TestQuery_IndexProjection(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "Query",
+			Input: &dynamodb.QueryInput{
+				TableName:              aws.String("people"),
+				IndexName:              aws.String("ByName"),
+				KeyConditionExpression: aws.String("#0 = :0"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "Name",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "John Appleseed"},
+				},
+			},
+			Output: &dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					{
+						"PK":   &types.AttributeValueMemberS{Value: "ABC"},
+						"SK":   &types.AttributeValueMemberS{Value: "123"},
+						"Name": &types.AttributeValueMemberS{Value: "John Appleseed"},
+					},
+				},
+			},
+		},
+	)
+
+	repo, err := newIndexedRepo(client)
+	assert.Nil(t, err)
+
+	_, err = repo.Query(context.Background(), dynamorm.NewQuery("Name", dynamorm.KeyValue("John Appleseed")).OnIndex("ByName"))
+	var projectionErr *dynamorm.ProjectionError
+	assert.ErrorAs(t, err, &projectionErr)
+	assert.Equal(t, "ByName", projectionErr.Index)
+	assert.Equal(t, []string{"Age"}, projectionErr.Missing)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestQuery_UnknownIndex(t *testing.T) {
+	client, _ := newStubbedClient()
+	repo, err := newIndexedRepo(client)
+	assert.Nil(t, err)
+
+	_, err = repo.Query(context.Background(), dynamorm.NewQuery("Email", dynamorm.KeyValue("john@appleseed.io")).OnIndex("ByEmail"))
+	assert.ErrorIs(t, err, dynamorm.ErrUnknownIndex)
+
+	_, err = repo.Query(context.Background(), dynamorm.NewQuery("Email", dynamorm.KeyValue("john@appleseed.io")).OnIndex("ByName"))
+	assert.Error(t, err)
+}
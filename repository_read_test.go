@@ -0,0 +1,210 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet_ReadOptions(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "GetItem",
+			Input: &dynamodb.GetItemInput{
+				Key:                      map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "P1"}},
+				TableName:                aws.String("profiles"),
+				ConsistentRead:           aws.Bool(true),
+				ProjectionExpression:     aws.String("#0, #1"),
+				ExpressionAttributeNames: map[string]string{"#0": "Name", "#1": "PK"},
+			},
+			Output: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"PK":   &types.AttributeValueMemberS{Value: "P1"},
+					"Name": &types.AttributeValueMemberS{Value: "Alice"},
+				},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+
+	profile, err := repo.Get(context.Background(), dynamorm.Key{"PK": dynamorm.KeyValue("P1")},
+		dynamorm.ConsistentRead(), dynamorm.Project("Name"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", profile.Name)
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestPartialModels(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "profiles", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+
+	for _, p := range []*examples.ProfileModel{
+		{ID: "P1", Name: "Alice", Bio: "Hello", Location: "Ohio"},
+		{ID: "P2", Name: "Bob", Location: "Indiana"},
+	} {
+		assert.NoError(t, repo.Create(ctx, p))
+	}
+
+	profile, err := repo.Get(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("P1")}, dynamorm.Project("Name"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", profile.Name)
+	assert.Empty(t, profile.Location)
+	assert.True(t, profile.Partial())
+	assert.True(t, profile.Loaded("Name"))
+	assert.False(t, profile.Loaded("Location"))
+
+	// Attributes that were not read cannot be changed.
+	profile.Name = "Alicia"
+	profile.Location = "Texas"
+	_, err = repo.Changes(profile)
+	assert.ErrorIs(t, err, dynamorm.ErrPartialModel)
+	assert.ErrorIs(t, repo.Update(ctx, profile), dynamorm.ErrPartialModel)
+	assert.Equal(t, dynamorm.KeyValue("Ohio"), client.Item("profiles", profile.Key())["Location"])
+
+	// Only the attributes that were read are compared and written.
+	profile.Location = ""
+	changes, err := repo.Changes(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, []dynamorm.Change{{Attribute: "Name", Old: dynamorm.KeyValue("Alice"), New: dynamorm.KeyValue("Alicia")}}, changes)
+	assert.NoError(t, repo.Update(ctx, profile))
+	assert.Equal(t, map[string]types.AttributeValue{
+		"PK":       dynamorm.KeyValue("P1"),
+		"Name":     dynamorm.KeyValue("Alicia"),
+		"Bio":      dynamorm.KeyValue("Hello"),
+		"Location": dynamorm.KeyValue("Ohio"),
+	}, client.Item("profiles", profile.Key()))
+
+	// Partial models cannot be written in full.
+	_, err = repo.Save(ctx, profile)
+	assert.ErrorIs(t, err, dynamorm.ErrPartialModel)
+
+	// Models read whole are not partial.
+	profile, err = repo.Get(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("P1")}, dynamorm.ConsistentRead())
+	assert.NoError(t, err)
+	assert.False(t, profile.Partial())
+	assert.True(t, profile.Loaded("Location"))
+	assert.Equal(t, "Ohio", profile.Location)
+
+	batch, err := repo.BatchGet(ctx, []dynamorm.Key{{"PK": dynamorm.KeyValue("P1")}, {"PK": dynamorm.KeyValue("P2")}},
+		dynamorm.Project("Location"), dynamorm.ConsistentRead())
+	assert.NoError(t, err)
+	assert.Len(t, batch.Items, 2)
+	for _, p := range batch.Items {
+		assert.True(t, p.Partial())
+		assert.Empty(t, p.Name)
+		assert.NotEmpty(t, p.Location)
+	}
+
+	// Nor can they be batch written, while the other models of the batch are.
+	whole := &examples.ProfileModel{ID: "P3", Name: "Carol", Location: "Utah"}
+	report, err := repo.BatchPut(ctx, []*examples.ProfileModel{batch.Items[0], whole})
+	assert.ErrorIs(t, err, dynamorm.ErrPartialModel)
+	assert.ErrorIs(t, report.Results[0].Err, dynamorm.ErrPartialModel)
+	assert.NoError(t, report.Results[1].Err)
+	assert.NotNil(t, client.Item("profiles", batch.Items[0].Key())["Name"])
+	assert.NotNil(t, client.Item("profiles", whole.Key()))
+
+	page, err := repo.Query(ctx, dynamorm.NewQuery("PK", dynamorm.KeyValue("P2")), dynamorm.Project("Name"), dynamorm.ConsistentRead())
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, "Bob", page.Items[0].Name)
+	assert.Empty(t, page.Items[0].Location)
+	assert.True(t, page.Items[0].Loaded("PK"))
+}
+
+// membershipModel is a model with a composite key whose attributes are not tagged as key attributes.
+type membershipModel struct {
+	Team   string `dynamodbav:"PK"`
+	Member string `dynamodbav:"SK"`
+	Name   string `dynamodbav:"Name"`
+	Role   string `dynamodbav:"Role"`
+
+	dynamorm.HasConditionExpression
+}
+
+func (m *membershipModel) Item() interface{} {
+	return m
+}
+
+func (m *membershipModel) Key() dynamorm.Key {
+	return dynamorm.Key{"PK": dynamorm.KeyValue(m.Team), "SK": dynamorm.KeyValue(m.Member)}
+}
+
+// invitationModel is a model whose key cannot be read from a zero-value model.
+type invitationModel struct {
+	Team    string `dynamodbav:"PK"`
+	Invitee *struct {
+		Email string `dynamodbav:"Email"`
+	} `dynamodbav:"Invitee"`
+	Name string `dynamodbav:"Name"`
+
+	dynamorm.HasConditionExpression
+}
+
+func (m *invitationModel) Item() interface{} {
+	return m
+}
+
+func (m *invitationModel) Key() dynamorm.Key {
+	return dynamorm.Key{"PK": dynamorm.KeyValue(m.Team), "SK": dynamorm.KeyValue(m.Invitee.Email)}
+}
+
+func TestQuery_Project_KeyAttributes(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	memberships, err := dynamorm.NewBuilder[*membershipModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+	assert.NoError(t, memberships.Create(ctx, &membershipModel{Team: "T1", Member: "M1", Name: "Alice", Role: "admin"}))
+
+	// The sort key is read too, though neither tagged nor queried, so the item can be written by the key of the model.
+	page, err := memberships.Query(ctx, dynamorm.NewQuery("PK", dynamorm.KeyValue("T1")), dynamorm.Project("Name"))
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, dynamorm.Key{"PK": dynamorm.KeyValue("T1"), "SK": dynamorm.KeyValue("M1")}, page.Items[0].Key())
+	assert.NoError(t, memberships.Patch(ctx, page.Items[0].Key(), expression.Set(expression.Name("Role"), expression.Value("owner"))))
+
+	// The key attributes of models whose key cannot be read from a zero value need to be declared.
+	invitations, err := dynamorm.NewBuilder[*invitationModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+	_, err = invitations.Query(ctx, dynamorm.NewQuery("PK", dynamorm.KeyValue("T1")), dynamorm.Project("Name"))
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+
+	invitations, err = dynamorm.NewBuilder[*invitationModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithKeySchema(dynamorm.KeySchema{
+			PartitionKey: dynamorm.KeyAttribute{Name: "PK", Type: types.ScalarAttributeTypeS},
+			SortKey:      dynamorm.KeyAttribute{Name: "SK", Type: types.ScalarAttributeTypeS},
+		}).
+		Build()
+	assert.Nil(t, err)
+	_, err = invitations.Query(ctx, dynamorm.NewQuery("PK", dynamorm.KeyValue("T2")), dynamorm.Project("Name"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,203 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+var teamsTable = dynamormtest.Table{Name: "teams", PartitionKey: "PK", SortKey: "SK"}
+
+// profileModel is a model with a related model in another table, so that it is saved within a transaction.
+type profileModel struct {
+	ID   string `dynamodbav:"PK"`
+	Name string `dynamodbav:"Name"`
+
+	dynamorm.HasConditionExpression
+}
+
+func (m *profileModel) Item() interface{} {
+	return m
+}
+
+func (m *profileModel) Key() dynamorm.Key {
+	return dynamorm.Key{"PK": dynamorm.KeyValue(m.ID)}
+}
+
+func (m *profileModel) Related() ([]dynamorm.Model, error) {
+	return []dynamorm.Model{&examples.TaggedModel{Team: "profiles", Member: m.ID, Role: m.Name}}, nil
+}
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable)
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	member := &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}
+	outcome, err := repo.Save(ctx, member)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveInserted, outcome)
+
+	member.Role = "member"
+	outcome, err = repo.Save(ctx, member)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveReplaced, outcome)
+	assert.Equal(t, dynamorm.KeyValue("member"), client.Item("teams", member.Key())["Role"])
+
+	// The condition expression of the model still applies.
+	condition, err := expression.NewBuilder().WithCondition(expression.Name("Role").Equal(expression.Value("admin"))).Build()
+	assert.NoError(t, err)
+	member.SetConditionExpression(&condition)
+	_, err = repo.Save(ctx, member)
+	assert.ErrorIs(t, err, dynamorm.ErrConditionFailed)
+
+	// Within a unit of work, the outcome is not known.
+	uow := dynamorm.NewUnitOfWork(client)
+	outcome, err = repo.Save(dynamorm.WithUnitOfWork(ctx, uow), &examples.TaggedModel{Team: "T1", Member: "M2"})
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveUnknown, outcome)
+	assert.NoError(t, uow.Commit(ctx))
+	assert.Len(t, client.Items("teams"), 2)
+}
+
+func TestSave_Related(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(teamsTable, dynamormtest.Table{Name: "profiles", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*profileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		WithRelatedTable(&examples.TaggedModel{}, "teams").
+		Build()
+	assert.Nil(t, err)
+
+	profile := &profileModel{ID: "P1", Name: "Alice"}
+	outcome, err := repo.Save(ctx, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveInserted, outcome)
+	assert.Len(t, client.Items("teams"), 1)
+
+	profile.Name = "Bob"
+	outcome, err = repo.Save(ctx, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveReplaced, outcome)
+	assert.Equal(t, dynamorm.KeyValue("Bob"), client.Item("profiles", profile.Key())["Name"])
+	assert.Equal(t, dynamorm.KeyValue("Bob"), client.Items("teams")[0]["Role"])
+
+	// The condition expression of the model is combined with the ones that tell inserts from replaces.
+	condition, err := expression.NewBuilder().WithCondition(expression.Name("Name").Equal(expression.Value("Alice"))).Build()
+	assert.NoError(t, err)
+	profile.SetConditionExpression(&condition)
+	profile.Name = "Carol"
+	_, err = repo.Save(ctx, profile)
+	assert.ErrorIs(t, err, dynamorm.ErrConditionFailed)
+	assert.Equal(t, dynamorm.KeyValue("Bob"), client.Item("profiles", profile.Key())["Name"])
+
+	condition, err = expression.NewBuilder().WithCondition(expression.Name("Name").Equal(expression.Value("Bob"))).Build()
+	assert.NoError(t, err)
+	profile.SetConditionExpression(&condition)
+	outcome, err = repo.Save(ctx, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveReplaced, outcome)
+	assert.Equal(t, dynamorm.KeyValue("Carol"), client.Item("profiles", profile.Key())["Name"])
+}
+
+func TestSave_StaleVersion(t *testing.T) {
+	for name, ctx := range map[string]context.Context{
+		"PutItem":            context.Background(),
+		"TransactWriteItems": dynamorm.WithIdempotencyToken(context.Background(), "save-doc-1"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := dynamormtest.NewClient(dynamormtest.Table{Name: "documents", PartitionKey: "PK"})
+			repo, err := dynamorm.NewBuilder[*examples.DocumentModel]().
+				WithClient(client).
+				WithTableName("documents").
+				Build()
+			assert.Nil(t, err)
+
+			doc := &examples.DocumentModel{ID: "doc-1", Body: "v1"}
+			assert.NoError(t, repo.Create(context.Background(), doc))
+			stale := *doc
+			for _, body := range []string{"v2", "v3", "v4"} {
+				doc.Body = body
+				assert.NoError(t, repo.Update(context.Background(), doc))
+			}
+
+			// Saving the stale model overwrites the item, at the version after the stored one rather than a lower one.
+			stale.Body = "stale"
+			outcome, err := repo.Save(ctx, &stale)
+			assert.NoError(t, err)
+			assert.Equal(t, dynamorm.SaveReplaced, outcome)
+			assert.Equal(t, int64(5), stale.Version)
+			assert.Equal(t, &types.AttributeValueMemberN{Value: "5"}, client.Item("documents", doc.Key())["Version"])
+
+			// So models loaded at the versions in between are still stale.
+			assert.ErrorIs(t, repo.Update(context.Background(), doc), dynamorm.ErrVersionConflict)
+		})
+	}
+}
+
+// racingClient deletes or recreates an item after each failed transaction, as a concurrent writer would.
+type racingClient struct {
+	*dynamormtest.Client
+	item  map[string]types.AttributeValue
+	races int
+}
+
+func (c *racingClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	out, err := c.Client.TransactWriteItems(ctx, params, optFns...)
+	if err == nil || c.races == 0 {
+		return out, err
+	}
+	c.races--
+	key := dynamorm.Key{"PK": c.item["PK"], "SK": c.item["SK"]}
+	if c.Item("teams", key) != nil {
+		_, _ = c.DeleteItem(ctx, &dynamodb.DeleteItemInput{TableName: aws.String("teams"), Key: key})
+	} else {
+		_, _ = c.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("teams"), Item: c.item})
+	}
+	return out, err
+}
+
+func TestSave_Concurrent(t *testing.T) {
+	ctx := dynamorm.WithIdempotencyToken(context.Background(), "save-M1")
+	member := &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}
+	client := &racingClient{Client: dynamormtest.NewClient(teamsTable)}
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+	outcome, err := repo.Save(context.Background(), member)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveInserted, outcome)
+	client.item = client.Item("teams", member.Key())
+
+	// The item is deleted after the insert fails, then recreated after the replace fails.
+	client.races = 2
+	member.Role = "member"
+	outcome, err = repo.Save(ctx, member)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveReplaced, outcome)
+	assert.Equal(t, dynamorm.KeyValue("member"), client.Item("teams", member.Key())["Role"])
+
+	// The item is deleted after the insert fails: it is inserted after all.
+	client.races = 1
+	member.Role = "owner"
+	outcome, err = repo.Save(dynamorm.WithIdempotencyToken(context.Background(), "save-M1-again"), member)
+	assert.NoError(t, err)
+	assert.Equal(t, dynamorm.SaveInserted, outcome)
+	assert.Equal(t, dynamorm.KeyValue("owner"), client.Item("teams", member.Key())["Role"])
+}
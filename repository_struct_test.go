@@ -0,0 +1,68 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyFromStruct(t *testing.T) {
+	model := &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}
+	assert.Equal(t, dynamorm.Key{
+		"PK": dynamorm.KeyValue("T1"),
+		"SK": dynamorm.KeyValue("M1"),
+	}, model.Key())
+
+	assert.Nil(t, dynamorm.KeyFromStruct(struct{ Name string }{"untagged"}))
+}
+
+func TestGet_StructModeler(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "GetItem",
+			Input: &dynamodb.GetItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "T1"},
+					"SK": &types.AttributeValueMemberS{Value: "M1"},
+				},
+				TableName: aws.String("teams"),
+			},
+			Output: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"PK":   &types.AttributeValueMemberS{Value: "T1"},
+					"SK":   &types.AttributeValueMemberS{Value: "M1"},
+					"Role": &types.AttributeValueMemberS{Value: "admin"},
+				},
+			},
+		},
+	)
+
+	// No modeler: the repository falls back to the struct modeler.
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	model, err := repo.Get(context.Background(), dynamorm.Key{
+		"PK": dynamorm.KeyValue("T1"),
+		"SK": dynamorm.KeyValue("M1"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"}, model)
+}
+
+func TestStructModeler_NotAStruct(t *testing.T) {
+	modeler := dynamorm.StructModeler[dynamorm.Model]()
+	_, err := modeler(map[string]types.AttributeValue{})
+	assert.Error(t, err)
+}
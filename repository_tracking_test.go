@@ -0,0 +1,152 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate_Tracked(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves repo.Get()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "GetItem",
+			Input: &dynamodb.GetItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "001"},
+				},
+				TableName: aws.String("profiles"),
+			},
+			Output: &dynamodb.GetItemOutput{
+				Item: map[string]types.AttributeValue{
+					"PK":       &types.AttributeValueMemberS{Value: "001"},
+					"Name":     &types.AttributeValueMemberS{Value: "John Appleseed"},
+					"Bio":      &types.AttributeValueMemberS{Value: "Plants trees."},
+					"Location": &types.AttributeValueMemberS{Value: "Ohio"},
+				},
+			},
+		},
+	)
+
+	// Serves repo.Update()
+	// Only the changed attributes are written.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "UpdateItem",
+			Input: &dynamodb.UpdateItemInput{
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "001"},
+				},
+				TableName:           aws.String("profiles"),
+				ConditionExpression: aws.String("attribute_exists (#0)"),
+				UpdateExpression:    aws.String("REMOVE #1\nSET #2 = :0\n"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+					"#1": "Bio",
+					"#2": "Location",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "Indiana"},
+				},
+			},
+			Output: &dynamodb.UpdateItemOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+
+	model, err := repo.Get(context.Background(), dynamorm.Key{"PK": dynamorm.KeyValue("001")})
+	assert.NoError(t, err)
+
+	changes, err := repo.Changes(model)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	model.Bio = ""
+	model.Location = "Indiana"
+
+	changes, err = repo.Changes(model)
+	assert.NoError(t, err)
+	assert.Equal(t, []dynamorm.Change{
+		{Attribute: "Bio", Old: dynamorm.KeyValue("Plants trees.")},
+		{Attribute: "Location", Old: dynamorm.KeyValue("Ohio"), New: dynamorm.KeyValue("Indiana")},
+	}, changes)
+
+	err = repo.Update(context.Background(), model)
+	assert.NoError(t, err)
+
+	// The model is snapshotted again after a successful update, and nothing else needs to be written.
+	changes, err = repo.Changes(model)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.NoError(t, repo.Update(context.Background(), model))
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+}
+
+func TestChanges_NotTracked(t *testing.T) {
+	client, _ := newStubbedClient()
+	repo, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+
+	_, err = repo.Changes(&examples.ProfileModel{ID: "001"})
+	assert.ErrorIs(t, err, dynamorm.ErrNotTracked)
+}
+
+func TestUpdate_Tracked_UnmodeledAttributes(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "profiles", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+
+	// The stored item has an attribute that the model does not hold, written by another service,
+	// and lacks the Location attribute that the model holds.
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("profiles"),
+		Item: map[string]types.AttributeValue{
+			"PK":        dynamorm.KeyValue("001"),
+			"Name":      dynamorm.KeyValue("John"),
+			"LastLogin": &types.AttributeValueMemberN{Value: "1700000000"},
+		},
+	})
+	assert.NoError(t, err)
+
+	model, err := repo.Get(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("001")})
+	assert.NoError(t, err)
+	changes, err := repo.Changes(model)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	model.Name = "Johnny"
+	changes, err = repo.Changes(model)
+	assert.NoError(t, err)
+	assert.Equal(t, []dynamorm.Change{{Attribute: "Name", Old: dynamorm.KeyValue("John"), New: dynamorm.KeyValue("Johnny")}}, changes)
+	assert.NoError(t, repo.Update(ctx, model))
+
+	// Only the changed attribute was written.
+	assert.Equal(t, map[string]types.AttributeValue{
+		"PK":        dynamorm.KeyValue("001"),
+		"Name":      dynamorm.KeyValue("Johnny"),
+		"LastLogin": &types.AttributeValueMemberN{Value: "1700000000"},
+	}, client.Item("profiles", model.Key()))
+}
@@ -0,0 +1,153 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+// uowTransaction is the transaction that the unit of work of the tests below commits.
+var uowTransaction = &dynamodb.TransactWriteItemsInput{
+	TransactItems: []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName: aws.String("profiles"),
+				Item: map[string]types.AttributeValue{
+					"PK":       &types.AttributeValueMemberS{Value: "001"},
+					"Name":     &types.AttributeValueMemberS{Value: "John Appleseed"},
+					"Location": &types.AttributeValueMemberS{Value: "Ohio"},
+				},
+				ConditionExpression:      aws.String("attribute_not_exists (#0)"),
+				ExpressionAttributeNames: map[string]string{"#0": "PK"},
+			},
+		},
+		{
+			Delete: &types.Delete{
+				TableName:                aws.String("teams"),
+				Key:                      map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "T1"}},
+				ConditionExpression:      aws.String("attribute_exists (#0)"),
+				ExpressionAttributeNames: map[string]string{"#0": "PK"},
+			},
+		},
+		{
+			ConditionCheck: &types.ConditionCheck{
+				TableName:                aws.String("teams"),
+				Key:                      map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "T2"}},
+				ConditionExpression:      aws.String("attribute_exists (#0)"),
+				ExpressionAttributeNames: map[string]string{"#0": "PK"},
+			},
+		},
+	},
+}
+
+// registerUnitOfWork registers the writes of uowTransaction with a unit of work bound to ctx.
+func registerUnitOfWork(t *testing.T, client *dynamodb.Client, ctx context.Context) *examples.ProfileModel {
+	profiles, err := dynamorm.NewBuilder[*examples.ProfileModel]().
+		WithClient(client).
+		WithTableName("profiles").
+		Build()
+	assert.Nil(t, err)
+	teams, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		Build()
+	assert.Nil(t, err)
+
+	profile := &examples.ProfileModel{ID: "001", Name: "John Appleseed", Location: "Ohio"}
+	assert.NoError(t, profiles.Create(ctx, profile))
+	assert.NoError(t, teams.Delete(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("T1")}))
+	assert.NoError(t, teams.ConditionCheck(ctx, dynamorm.Key{"PK": dynamorm.KeyValue("T2")},
+		expression.AttributeExists(expression.Name("PK"))))
+
+	// Nothing is written, nor completed, until the unit of work is committed.
+	_, err = profiles.Changes(profile)
+	assert.ErrorIs(t, err, dynamorm.ErrNotTracked)
+	return profile
+}
+
+func TestUnitOfWork(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input:         uowTransaction,
+			Output:        &dynamodb.TransactWriteItemsOutput{},
+		},
+	)
+
+	uow := dynamorm.NewUnitOfWork(client)
+	ctx := dynamorm.WithUnitOfWork(context.Background(), uow)
+	profile := registerUnitOfWork(t, client, ctx)
+	assert.Equal(t, 3, uow.Len())
+
+	committed := false
+	uow.AfterCommit(func(ctx context.Context) {
+		// Hooks are called without holding the unit of work, so they can use it.
+		assert.Equal(t, 3, uow.Len())
+		committed = true
+	})
+	assert.NoError(t, uow.Commit(context.Background()))
+	assert.NoError(t, stubber.VerifyAllStubsCalled())
+	assert.True(t, committed)
+
+	// The Create completed on commit: the model is now tracked.
+	profiles, _ := dynamorm.NewBuilder[*examples.ProfileModel]().WithClient(client).WithTableName("profiles").Build()
+	changes, err := profiles.Changes(profile)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// The unit of work is spent.
+	assert.ErrorIs(t, uow.Commit(context.Background()), dynamorm.ErrUnitOfWorkCommitted)
+	assert.ErrorIs(t, profiles.Delete(ctx, profile.Key()), dynamorm.ErrUnitOfWorkCommitted)
+}
+
+func TestUnitOfWork_Canceled(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "TransactWriteItems",
+			Input:         uowTransaction,
+			Error: &testtools.StubError{
+				Err: &types.TransactionCanceledException{
+					Message: aws.String("Transaction cancelled"),
+					CancellationReasons: []types.CancellationReason{
+						{Code: aws.String("None")},
+						{Code: aws.String("ConditionalCheckFailed")},
+						{Code: aws.String("None")},
+					},
+				},
+			},
+		},
+	)
+
+	uow := dynamorm.NewUnitOfWork(client)
+	ctx := dynamorm.WithUnitOfWork(context.Background(), uow)
+	profile := registerUnitOfWork(t, client, ctx)
+
+	uow.AfterCommit(func(ctx context.Context) {
+		t.Error("hooks are not called when the commit fails")
+	})
+	err := uow.Commit(context.Background())
+	assert.ErrorIs(t, err, dynamorm.ErrDoesNotExist)
+
+	// Failures identify the writes of every repository.
+	var txErr *dynamorm.TransactionError
+	assert.ErrorAs(t, err, &txErr)
+	assert.Len(t, txErr.Failures, 1)
+	assert.Equal(t, 1, txErr.Failures[0].Index)
+	assert.True(t, txErr.Failures[0].Primary)
+
+	// The Create did not complete.
+	profiles, _ := dynamorm.NewBuilder[*examples.ProfileModel]().WithClient(client).WithTableName("profiles").Build()
+	_, err = profiles.Changes(profile)
+	assert.ErrorIs(t, err, dynamorm.ErrNotTracked)
+}
@@ -0,0 +1,184 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bezhermoso/dynamorm"
+	"github.com/bezhermoso/dynamorm/dynamormtest"
+	"github.com/bezhermoso/dynamorm/internal/examples"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate_Versioned(t *testing.T) {
+	client, stubber := newStubbedClient()
+
+	// Serves the first repo.Update()
+	// Asserts that the item is still at the version it was loaded with, and bumps it.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Input: &dynamodb.PutItemInput{
+				Item: map[string]types.AttributeValue{
+					"PK":      &types.AttributeValueMemberS{Value: "doc-1"},
+					"Body":    &types.AttributeValueMemberS{Value: "Hello, world!"},
+					"Version": &types.AttributeValueMemberN{Value: "4"},
+				},
+				TableName:           aws.String("documents"),
+				ConditionExpression: aws.String("(attribute_exists (#0)) AND (#1 = :0)"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+					"#1": "Version",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberN{Value: "3"},
+				},
+			},
+			Output: &dynamodb.PutItemOutput{},
+		},
+	)
+
+	// Serves the second repo.Update()
+	// Someone else updated the item in the meantime.
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Error: &testtools.StubError{
+				Err: &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+			},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.DocumentModel]().
+		WithClient(client).
+		WithTableName("documents").
+		Build()
+	assert.Nil(t, err)
+
+	doc := &examples.DocumentModel{ID: "doc-1", Body: "Hello, world!", Version: 3}
+	err = repo.Update(context.Background(), doc)
+	assert.NoError(t, err)
+	// The new version is written back into the model.
+	assert.Equal(t, int64(4), doc.Version)
+
+	err = repo.Update(context.Background(), doc)
+	assert.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+	assert.Equal(t, int64(4), doc.Version)
+}
+
+func TestPatch_Versioned(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "documents", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*examples.DocumentModel]().
+		WithClient(client).
+		WithTableName("documents").
+		Build()
+	assert.Nil(t, err)
+
+	doc := &examples.DocumentModel{ID: "doc-1", Body: "Hello, world!"}
+	assert.NoError(t, repo.Create(ctx, doc))
+	assert.Equal(t, int64(1), doc.Version)
+
+	// Patching the item increments the version that the model implementing Versioned names.
+	err = repo.Patch(ctx, doc.Key(), expression.Set(expression.Name("Body"), expression.Value("Hello, patch!")))
+	assert.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "2"}, client.Item("documents", doc.Key())["Version"])
+
+	// The model is now stale: updating it would overwrite the patch.
+	doc.Body = "Hello, update!"
+	assert.ErrorIs(t, repo.Update(ctx, doc), dynamorm.ErrVersionConflict)
+	assert.Equal(t, &types.AttributeValueMemberS{Value: "Hello, patch!"}, client.Item("documents", doc.Key())["Body"])
+}
+
+func TestCreate_VersionAttribute(t *testing.T) {
+	client, stubber := newStubbedClient()
+	stubber.Add(
+		testtools.Stub{
+			OperationName: "PutItem",
+			Input: &dynamodb.PutItemInput{
+				Item: map[string]types.AttributeValue{
+					"PK":       &types.AttributeValueMemberS{Value: "T1"},
+					"SK":       &types.AttributeValueMemberS{Value: "M1"},
+					"Role":     &types.AttributeValueMemberS{Value: "admin"},
+					"Revision": &types.AttributeValueMemberN{Value: "1"},
+				},
+				TableName:           aws.String("teams"),
+				ConditionExpression: aws.String("(attribute_not_exists (#0)) AND (attribute_not_exists (#1))"),
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+					"#1": "SK",
+				},
+			},
+			Output: &dynamodb.PutItemOutput{},
+		},
+	)
+
+	repo, err := dynamorm.NewBuilder[*examples.TaggedModel]().
+		WithClient(client).
+		WithTableName("teams").
+		WithVersionAttribute("Revision").
+		Build()
+	assert.Nil(t, err)
+
+	err = repo.Create(context.Background(), &examples.TaggedModel{Team: "T1", Member: "M1", Role: "admin"})
+	assert.NoError(t, err)
+}
+
+// revisionModel is a versioned model whose version attribute cannot be told from a zero-value model.
+type revisionModel struct {
+	ID     string `dynamodbav:"PK" dynamorm:"pk"`
+	Schema *struct {
+		VersionAttribute string
+	} `dynamodbav:"-"`
+
+	dynamorm.HasConditionExpression
+}
+
+func (m *revisionModel) Item() interface{} {
+	return m
+}
+
+func (m *revisionModel) Key() dynamorm.Key {
+	return dynamorm.KeyFromStruct(m)
+}
+
+func (m *revisionModel) VersionAttribute() string {
+	return m.Schema.VersionAttribute
+}
+
+func TestBuild_VersionAttributePanics(t *testing.T) {
+	// The version attribute is resolved once, when the repository is built, rather than on every Patch.
+	_, err := dynamorm.NewBuilder[*revisionModel]().
+		WithClient(dynamormtest.NewClient()).
+		WithTableName("revisions").
+		Build()
+	assert.ErrorIs(t, err, dynamorm.ErrInvalidConfiguration)
+	assert.ErrorContains(t, err, "VersionAttribute() of a zero-value *dynamorm_test.revisionModel panicked")
+}
+
+func TestDeleteModel_Versioned(t *testing.T) {
+	ctx := context.Background()
+	client := dynamormtest.NewClient(dynamormtest.Table{Name: "documents", PartitionKey: "PK"})
+	repo, err := dynamorm.NewBuilder[*examples.DocumentModel]().
+		WithClient(client).
+		WithTableName("documents").
+		Build()
+	assert.Nil(t, err)
+
+	doc := &examples.DocumentModel{ID: "doc-1", Body: "Hello, world!"}
+	assert.NoError(t, repo.Create(ctx, doc))
+
+	// A model at another version than the stored one is stale: deleting it would discard the changes it missed.
+	stale := &examples.DocumentModel{ID: "doc-1"}
+	assert.ErrorIs(t, repo.DeleteModel(ctx, stale), dynamorm.ErrVersionConflict)
+	assert.NotNil(t, client.Item("documents", doc.Key()))
+
+	assert.NoError(t, repo.DeleteModel(ctx, doc))
+	assert.Nil(t, client.Item("documents", doc.Key()))
+}
@@ -0,0 +1,251 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// MaxTransactItems is the maximum number of items in a single DynamoDB transaction.
+	MaxTransactItems = 100
+	// MaxTransactSize is the maximum aggregate size, in bytes, of the items in a single DynamoDB transaction.
+	MaxTransactSize = 4 * 1024 * 1024
+)
+
+// SagaError is returned when a write that was split into several transactions fails part way through.
+//
+// The batches committed before the failure are compensated for, in reverse order: created items are deleted,
+// and replaced or deleted items are restored to their prior state. Compensation is best-effort, as other writers
+// may have seen, or changed, the intermediate state.
+type SagaError struct {
+	// Batches The number of transactions the write was split into.
+	Batches int
+	// Committed The number of batches that committed before the failure, and were compensated for.
+	// The batch at this index is the one that failed.
+	Committed int
+	// Err The error of the failed batch.
+	Err error
+	// CompensationErr The error that occurred while compensating, if any. When set, the committed batches
+	// may not have been (fully) rolled back.
+	CompensationErr error
+}
+
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("batch %d of %d failed after %d committed: %v", e.Committed+1, e.Batches, e.Committed, e.Err)
+	if e.CompensationErr != nil {
+		msg += fmt.Sprintf("; compensation failed: %v", e.CompensationErr)
+	}
+	return msg
+}
+
+func (e *SagaError) Unwrap() []error {
+	errs := []error{e.Err}
+	if e.CompensationErr != nil {
+		errs = append(errs, e.CompensationErr)
+	}
+	return errs
+}
+
+// commitSaga writes the items in several transactions, each within the limits of DynamoDB.
+// The prior state of the items of each batch is read right before the batch is committed, so that
+// it can be restored should a later batch fail.
+//
+// With an idempotency token, each batch is sent with a token derived from it.
+func (r *repositoryImpl[T]) commitSaga(ctx context.Context, writes []write, token string) error {
+	batches := chunkWrites(writes)
+	var compensations []types.TransactWriteItem
+	for i, batch := range batches {
+		priors, err := r.readPriorImages(ctx, batch)
+		if err == nil {
+			err = r.commitTransaction(ctx, batch, deriveToken(token, fmt.Sprintf("batch-%d", i)))
+		}
+		if err != nil {
+			sagaErr := &SagaError{Batches: len(batches), Committed: i, Err: err}
+			sagaErr.CompensationErr = r.compensate(ctx, compensations)
+			return sagaErr
+		}
+		compensations = append(compensations, compensationsFor(batch, priors)...)
+	}
+	return nil
+}
+
+// readPriorImages reads the current state of the items of a batch, in a single consistent read.
+// The images are positional; nil images are for items that do not exist, or that are only condition checks.
+func (r *repositoryImpl[T]) readPriorImages(ctx context.Context, batch []write) ([]map[string]types.AttributeValue, error) {
+	input := &dynamodb.TransactGetItemsInput{}
+	positions := make([]int, 0, len(batch))
+	for i, w := range batch {
+		key, tableName := writeTarget(w)
+		if key == nil {
+			continue
+		}
+		input.TransactItems = append(input.TransactItems, types.TransactGetItem{
+			Get: &types.Get{Key: key, TableName: tableName},
+		})
+		positions = append(positions, i)
+	}
+
+	priors := make([]map[string]types.AttributeValue, len(batch))
+	if len(input.TransactItems) == 0 {
+		return priors, nil
+	}
+	out, err := r.client.TransactGetItems(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	for i, response := range out.Responses {
+		if i < len(positions) && len(response.Item) > 0 {
+			priors[positions[i]] = response.Item
+		}
+	}
+	return priors, nil
+}
+
+// compensationsFor constructs the writes that undo a committed batch, given the prior state of its items.
+func compensationsFor(batch []write, priors []map[string]types.AttributeValue) []types.TransactWriteItem {
+	compensations := make([]types.TransactWriteItem, 0, len(batch))
+	for i, w := range batch {
+		key, tableName := writeTarget(w)
+		if key == nil {
+			continue
+		}
+		if priors[i] == nil {
+			compensations = append(compensations, types.TransactWriteItem{
+				Delete: &types.Delete{Key: key, TableName: tableName},
+			})
+		} else {
+			compensations = append(compensations, types.TransactWriteItem{
+				Put: &types.Put{Item: priors[i], TableName: tableName},
+			})
+		}
+	}
+	return compensations
+}
+
+// compensate applies compensations in reverse order, in as many transactions as needed.
+func (r *repositoryImpl[T]) compensate(ctx context.Context, compensations []types.TransactWriteItem) error {
+	if len(compensations) == 0 {
+		return nil
+	}
+	reversed := make([]write, 0, len(compensations))
+	for i := len(compensations) - 1; i >= 0; i-- {
+		reversed = append(reversed, write{item: compensations[i]})
+	}
+	var errs []error
+	for _, batch := range chunkWrites(reversed) {
+		if err := r.commitTransaction(ctx, batch, ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeTarget returns the key and table of the item that a write changes. Returns a nil key for condition checks.
+func writeTarget(w write) (Key, *string) {
+	switch {
+	case w.item.Put != nil:
+		return w.model.Key(), w.item.Put.TableName
+	case w.item.Update != nil:
+		return w.item.Update.Key, w.item.Update.TableName
+	case w.item.Delete != nil:
+		return w.item.Delete.Key, w.item.Delete.TableName
+	}
+	return nil, nil
+}
+
+// chunkWrites splits writes into batches that are each within the limits of a transaction, preserving order.
+func chunkWrites(writes []write) [][]write {
+	var batches [][]write
+	var batch []write
+	batchSize := 0
+	for _, w := range writes {
+		size := writeSize(w)
+		if len(batch) > 0 && (len(batch) == MaxTransactItems || batchSize+size > MaxTransactSize) {
+			batches = append(batches, batch)
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, w)
+		batchSize += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// transactionSize estimates the aggregate size of the items of a transaction, in bytes.
+func transactionSize(writes []write) int {
+	size := 0
+	for _, w := range writes {
+		size += writeSize(w)
+	}
+	return size
+}
+
+// writeSize estimates the size of a write, in bytes, following DynamoDB's rules for item sizes.
+func writeSize(w write) int {
+	switch {
+	case w.item.Put != nil:
+		return itemSize(w.item.Put.Item)
+	case w.item.Update != nil:
+		return itemSize(w.item.Update.Key) + itemSize(w.item.Update.ExpressionAttributeValues) + len(aws.ToString(w.item.Update.UpdateExpression))
+	case w.item.Delete != nil:
+		return itemSize(w.item.Delete.Key)
+	case w.item.ConditionCheck != nil:
+		return itemSize(w.item.ConditionCheck.Key)
+	}
+	return 0
+}
+
+// itemSize estimates the size of an item, in bytes: the lengths of its attribute names plus the sizes of its values.
+func itemSize(item map[string]types.AttributeValue) int {
+	size := 0
+	for k, v := range item {
+		size += len(k) + attributeValueSize(v)
+	}
+	return size
+}
+
+func attributeValueSize(av types.AttributeValue) int {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return len(v.Value)/2 + 1
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberSS:
+		size := 0
+		for _, s := range v.Value {
+			size += len(s)
+		}
+		return size
+	case *types.AttributeValueMemberNS:
+		size := 0
+		for _, n := range v.Value {
+			size += len(n)/2 + 1
+		}
+		return size
+	case *types.AttributeValueMemberBS:
+		size := 0
+		for _, b := range v.Value {
+			size += len(b)
+		}
+		return size
+	case *types.AttributeValueMemberL:
+		size := 3
+		for _, e := range v.Value {
+			size += 1 + attributeValueSize(e)
+		}
+		return size
+	case *types.AttributeValueMemberM:
+		return 3 + itemSize(v.Value) + len(v.Value)
+	}
+	// BOOL and NULL.
+	return 1
+}
@@ -0,0 +1,262 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SaveOutcome tells whether Save inserted a new item or replaced an existing one.
+type SaveOutcome int
+
+const (
+	// SaveUnknown The outcome is not known, e.g. because the model was saved within a UnitOfWork.
+	SaveUnknown SaveOutcome = iota
+	// SaveInserted The item did not exist, and was inserted.
+	SaveInserted
+	// SaveReplaced The item existed, and was replaced.
+	SaveReplaced
+)
+
+func (o SaveOutcome) String() string {
+	switch o {
+	case SaveInserted:
+		return "inserted"
+	case SaveReplaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+// Save implements Repository.
+func (r *repositoryImpl[T]) Save(ctx context.Context, model T) (SaveOutcome, error) {
+	key := model.Key()
+	if err := r.checkKey(key); err != nil {
+		return SaveUnknown, err
+	}
+
+	// Putting a partial model would overwrite the attributes that were not read.
+	if t, ok := Model(model).(tracker); ok && t.Partial() {
+		return SaveUnknown, ErrPartialModel
+	}
+
+	if err := before(ctx, operationSave, model); err != nil {
+		return SaveUnknown, err
+	}
+	putItem, err := r.constructPutItem(model)
+	if err != nil {
+		return SaveUnknown, err
+	}
+
+	// The version is incremented, but not asserted: Save overwrites whatever version is stored. It is however never
+	// lowered: should a newer version be stored, the item is written again at the version after it.
+	versionAttr := r.versionAttribute(model)
+	var version int64
+	if versionAttr != "" {
+		if version, err = bumpVersion(putItem.Item, versionAttr); err != nil {
+			return SaveUnknown, err
+		}
+		version++
+	}
+
+	writes := []write{{model: model, primary: true, item: types.TransactWriteItem{Put: putItem}, conditionErr: ErrConditionFailed}}
+	writes, err = r.appendPutsFromRelatedModels(ctx, operationSave, writes, model)
+	if err != nil {
+		return SaveUnknown, err
+	}
+	after := func() {
+		if versionAttr != "" {
+			writeBackVersion(model, putItem.Item, versionAttr)
+		}
+		track(model, putItem.Item)
+	}
+
+	// Within a UnitOfWork, the outcome is only known once it is committed, which it cannot report. Nor can the item
+	// be written again, so a newer stored version fails the commit.
+	if _, ok := UnitOfWorkFrom(ctx); ok {
+		if versionAttr != "" {
+			put := *putItem
+			if err := andCondition(&put, versionBelow(versionAttr, version)); err != nil {
+				return SaveUnknown, err
+			}
+			writes[0].item = types.TransactWriteItem{Put: &put}
+		}
+		return SaveUnknown, r.commit(ctx, writes, after)
+	}
+
+	token, err := r.idempotencyToken(ctx)
+	if err != nil {
+		return SaveUnknown, err
+	}
+
+	// A single item is written with PutItem, which returns the item it replaced, if any. Transactions cannot return
+	// the items they replace. Instead, the item is first written on the condition that it does not exist. Should that
+	// condition fail, it is written again on the condition that it does, and so on should the item be deleted or
+	// created concurrently. Each attempt is a different request, so it needs its own idempotency token.
+	transactional := len(writes) > 1 || token != ""
+	exists := false
+	for attempt := 0; ; attempt++ {
+		put := *putItem
+		var conditions []expression.ConditionBuilder
+		if transactional {
+			if exists {
+				conditions = append(conditions, key.existsCondition())
+			} else {
+				conditions = append(conditions, key.notExistsCondition())
+			}
+		}
+		if versionAttr != "" {
+			conditions = append(conditions, versionBelow(versionAttr, version))
+		}
+		if len(conditions) > 0 {
+			condition := conditions[0]
+			if len(conditions) > 1 {
+				condition = expression.And(conditions[0], conditions[1], conditions[2:]...)
+			}
+			if err := andCondition(&put, condition); err != nil {
+				return SaveUnknown, err
+			}
+			// The stored item tells which condition failed.
+			put.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		}
+		writes[0].item = types.TransactWriteItem{Put: &put}
+
+		outcome := SaveInserted
+		if exists {
+			outcome = SaveReplaced
+		}
+		if !transactional {
+			writes[0].returnOld = func(old map[string]types.AttributeValue) {
+				if len(old) > 0 {
+					outcome = SaveReplaced
+				}
+			}
+		}
+		attemptCtx := ctx
+		if token != "" && attempt > 0 {
+			attemptCtx = WithIdempotencyToken(ctx, deriveToken(token, "save/"+strconv.Itoa(attempt)))
+		}
+		err := r.commit(attemptCtx, writes, after)
+		if err == nil {
+			return outcome, nil
+		}
+
+		// Only write again if the item was deleted or created, or a newer version was stored, since the attempt.
+		// Otherwise the model's own condition failed.
+		stored, ok := primaryConditionFailure(err)
+		if !ok || attempt+1 >= maxSaveAttempts {
+			return SaveUnknown, err
+		}
+		retry := transactional && (len(stored) > 0) != exists
+		exists = len(stored) > 0
+		if versionAttr != "" && exists {
+			current, err := itemVersion(stored, versionAttr)
+			if err != nil {
+				return SaveUnknown, err
+			}
+			if current >= version {
+				version = current + 1
+				putItem.Item[versionAttr] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+				retry = true
+			}
+		}
+		if !retry {
+			return SaveUnknown, err
+		}
+	}
+}
+
+// maxSaveAttempts is how many times Save writes an item at most, while its existence or version changes concurrently.
+const maxSaveAttempts = 5
+
+// primaryConditionFailure reports whether a write failed because the condition of its primary item failed, returning
+// the stored item if the write asked for it, which is empty if there was none.
+func primaryConditionFailure(err error) (map[string]types.AttributeValue, bool) {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		// Single-item writes only write the primary item.
+		return ccf.Item, true
+	}
+	var txErr *TransactionError
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &txErr) || !errors.As(err, &tce) {
+		return nil, false
+	}
+	for _, f := range txErr.Failures {
+		if f.Primary && f.Code == "ConditionalCheckFailed" && f.Index < len(tce.CancellationReasons) {
+			return tce.CancellationReasons[f.Index].Item, true
+		}
+	}
+	return nil, false
+}
+
+// placeholderPattern matches the placeholders of built expressions, e.g. #0 and :0.
+var placeholderPattern = regexp.MustCompile(`[#:][0-9]+`)
+
+// andCondition adds a condition to the condition expression of a put, if any, renaming the placeholders of the
+// condition so that they do not collide with the ones of the put.
+func andCondition(put *types.Put, condition expression.ConditionBuilder) error {
+	expr, err := expression.NewBuilder().WithCondition(condition).Build()
+	if err != nil {
+		return err
+	}
+	if put.ConditionExpression == nil {
+		put.ConditionExpression = expr.Condition()
+		put.ExpressionAttributeNames = expr.Names()
+		put.ExpressionAttributeValues = expr.Values()
+		return nil
+	}
+
+	prefix := "k"
+	for placeholdersCollide(put, prefix) {
+		prefix += "k"
+	}
+	rename := func(placeholder string) string {
+		return placeholder[:1] + prefix + placeholder[1:]
+	}
+
+	names := make(map[string]string, len(put.ExpressionAttributeNames)+len(expr.Names()))
+	for k, v := range put.ExpressionAttributeNames {
+		names[k] = v
+	}
+	for k, v := range expr.Names() {
+		names[rename(k)] = v
+	}
+	values := make(map[string]types.AttributeValue, len(put.ExpressionAttributeValues)+len(expr.Values()))
+	for k, v := range put.ExpressionAttributeValues {
+		values[k] = v
+	}
+	for k, v := range expr.Values() {
+		values[rename(k)] = v
+	}
+	if len(values) == 0 {
+		values = nil
+	}
+
+	renamed := placeholderPattern.ReplaceAllStringFunc(*expr.Condition(), rename)
+	put.ConditionExpression = aws.String("(" + *put.ConditionExpression + ") AND (" + renamed + ")")
+	put.ExpressionAttributeNames = names
+	put.ExpressionAttributeValues = values
+	return nil
+}
+
+// placeholdersCollide reports whether any placeholder of the put starts with the prefix.
+func placeholdersCollide(put *types.Put, prefix string) bool {
+	for k := range put.ExpressionAttributeNames {
+		if strings.HasPrefix(k, "#"+prefix) {
+			return true
+		}
+	}
+	for k := range put.ExpressionAttributeValues {
+		if strings.HasPrefix(k, ":"+prefix) {
+			return true
+		}
+	}
+	return false
+}
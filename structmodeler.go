@@ -0,0 +1,128 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// StructModeler returns a Modeler for models that are pointers to structs whose fields are the item's attributes,
+// e.g. models whose Item() returns the model itself.
+// Items are unmarshalled into a freshly allocated struct according to its `dynamodbav` tags.
+//
+// This is the modeler that the Builder uses when WithModeler is not called.
+func StructModeler[T Model]() Modeler[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
+		return func(item map[string]types.AttributeValue) (T, error) {
+			var zero T
+			return zero, fmt.Errorf("struct modeler: %v is not a pointer to a struct", typ)
+		}
+	}
+	return func(item map[string]types.AttributeValue) (T, error) {
+		v := reflect.New(typ.Elem())
+		if err := attributevalue.UnmarshalMap(item, v.Interface()); err != nil {
+			var zero T
+			return zero, err
+		}
+		return v.Interface().(T), nil
+	}
+}
+
+// KeyFromStruct derives a Key from the fields of a struct (or pointer to struct) that are tagged with
+// `dynamorm:"pk"` (partition key) or `dynamorm:"sk"` (sort key). The attribute names are taken from the
+// `dynamodbav` tags of the fields, falling back to the field names.
+//
+// It lets models implement Key() without boilerplate:
+//
+//	type Person struct {
+//		ID   string `dynamodbav:"PK" dynamorm:"pk"`
+//		Name string `dynamodbav:"Name"`
+//	}
+//
+//	func (p *Person) Key() dynamorm.Key { return dynamorm.KeyFromStruct(p) }
+//
+// Returns nil if v is not a struct, or if it has no tagged fields.
+func KeyFromStruct(v interface{}) Key {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := structKeyFieldsOf(rv.Type())
+	if len(fields) == 0 {
+		return nil
+	}
+	key := make(Key, len(fields))
+	for _, f := range fields {
+		fv, err := rv.FieldByIndexErr(f.index)
+		if err != nil {
+			return nil
+		}
+		av, err := attributevalue.Marshal(fv.Interface())
+		if err != nil {
+			return nil
+		}
+		key[f.attribute] = av
+	}
+	return key
+}
+
+// structKeyField is a struct field that holds a key attribute.
+type structKeyField struct {
+	// attribute The name of the attribute.
+	attribute string
+	// role Either "pk" or "sk".
+	role string
+	// index The index sequence of the field, for reflect.Value.FieldByIndex.
+	index []int
+}
+
+// structKeyFieldsCache caches the key fields of struct types, as reflect.Type -> []structKeyField.
+var structKeyFieldsCache sync.Map
+
+// structKeyFieldsOf returns the key fields of a struct type, partition key first.
+func structKeyFieldsOf(typ reflect.Type) []structKeyField {
+	if cached, ok := structKeyFieldsCache.Load(typ); ok {
+		return cached.([]structKeyField)
+	}
+	fields := collectStructKeyFields(typ, nil)
+	// Keep the partition key ahead of the sort key, regardless of field order.
+	if len(fields) == 2 && fields[0].role == "sk" {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
+	structKeyFieldsCache.Store(typ, fields)
+	return fields
+}
+
+func collectStructKeyFields(typ reflect.Type, parent []int) []structKeyField {
+	var fields []structKeyField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		index := append(append([]int{}, parent...), i)
+		// Descend into embedded structs, as the attributevalue encoder does.
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("dynamodbav") == "" {
+			fields = append(fields, collectStructKeyFields(sf.Type, index)...)
+			continue
+		}
+		role := sf.Tag.Get("dynamorm")
+		if role != "pk" && role != "sk" {
+			continue
+		}
+		attribute := sf.Name
+		if name, _, _ := strings.Cut(sf.Tag.Get("dynamodbav"), ","); name != "" && name != "-" {
+			attribute = name
+		}
+		fields = append(fields, structKeyField{attribute: attribute, role: role, index: index})
+	}
+	return fields
+}
@@ -0,0 +1,136 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Tracked is a convenience struct that models can embed to opt into change tracking.
+//
+// The repository snapshots the item that a tracked model was loaded from (or last saved as), so that
+// Repository.Update only writes the attributes that changed since, using the Update operation instead of Put.
+// Repository.Changes reports those attributes.
+//
+// Models read with the Project option are partial: only the attributes that were read can be changed. Should any
+// other attribute change, Repository.Changes and Repository.Update fail with ErrPartialModel.
+type Tracked struct {
+	original map[string]types.AttributeValue
+	// loaded The attributes that were read, if the model was read with a projection. Nil if it was read whole.
+	loaded map[string]bool
+}
+
+// Partial reports whether the model was read with a projection, so that only some of its attributes were read.
+func (t *Tracked) Partial() bool {
+	return t.loaded != nil
+}
+
+// Loaded reports whether the attribute was read, which is always the case if the model is not partial.
+func (t *Tracked) Loaded(attribute string) bool {
+	return t.loaded == nil || t.loaded[attribute]
+}
+
+func (t *Tracked) snapshot() map[string]types.AttributeValue {
+	return t.original
+}
+
+func (t *Tracked) setSnapshot(item map[string]types.AttributeValue) {
+	t.original = item
+}
+
+func (t *Tracked) setLoaded(attributes []string) {
+	t.loaded = nil
+	if attributes != nil {
+		t.loaded = make(map[string]bool, len(attributes))
+		for _, attr := range attributes {
+			t.loaded[attr] = true
+		}
+	}
+}
+
+// tracker is implemented by models that embed Tracked.
+type tracker interface {
+	snapshot() map[string]types.AttributeValue
+	setSnapshot(item map[string]types.AttributeValue)
+	setLoaded(attributes []string)
+	Partial() bool
+	Loaded(attribute string) bool
+}
+
+// track snapshots the whole item of a model, if the model opted into change tracking.
+func track(model Model, item map[string]types.AttributeValue) {
+	trackLoaded(model, item, nil)
+}
+
+// trackLoaded snapshots the item of a model that was read with the projection, if the model opted into change
+// tracking. A nil projection means that the item was read whole.
+func trackLoaded(model Model, item map[string]types.AttributeValue, projection []string) {
+	if t, ok := model.(tracker); ok {
+		t.setSnapshot(item)
+		t.setLoaded(projection)
+	}
+}
+
+// diffLoaded returns the changes of a tracked model. Partial models fail with ErrPartialModel if an attribute that
+// was not read changed, since writing it would overwrite a value that was never read.
+func diffLoaded(t tracker, current map[string]types.AttributeValue) ([]Change, error) {
+	changes := diffItems(t.snapshot(), current)
+	for _, c := range changes {
+		if !t.Loaded(c.Attribute) {
+			return nil, fmt.Errorf("%w: %q was changed but not read", ErrPartialModel, c.Attribute)
+		}
+	}
+	return changes, nil
+}
+
+// Change is an attribute whose value differs from the one the model was loaded with.
+type Change struct {
+	// Attribute The name of the attribute.
+	Attribute string
+	// Old The value the model was loaded with. Nil if the attribute was added.
+	Old types.AttributeValue
+	// New The current value. Nil if the attribute was removed.
+	New types.AttributeValue
+}
+
+// diffItems returns the attributes that differ between the original and the current item, sorted by name.
+func diffItems(original, current map[string]types.AttributeValue) []Change {
+	changes := []Change{}
+	for k, v := range current {
+		if old, ok := original[k]; !ok || !reflect.DeepEqual(old, v) {
+			changes = append(changes, Change{Attribute: k, Old: original[k], New: v})
+		}
+	}
+	for k, old := range original {
+		if _, ok := current[k]; !ok {
+			changes = append(changes, Change{Attribute: k, Old: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Attribute < changes[j].Attribute
+	})
+	return changes
+}
+
+// updateFromChanges compiles changes into an update that SETs changed attributes and REMOVEs removed ones.
+// Key attributes are skipped, as they cannot be updated.
+// Returns false if there is nothing to update.
+func updateFromChanges(changes []Change, key Key) (expression.UpdateBuilder, bool) {
+	var update expression.UpdateBuilder
+	hasUpdates := false
+	for _, c := range changes {
+		if _, ok := key[c.Attribute]; ok {
+			continue
+		}
+		if c.New == nil {
+			update = update.Remove(expression.Name(c.Attribute))
+		} else {
+			update = update.Set(expression.Name(c.Attribute), expression.Value(c.New))
+		}
+		hasUpdates = true
+	}
+	return update, hasUpdates
+}
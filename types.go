@@ -2,6 +2,13 @@ package dynamorm
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -32,7 +39,18 @@ type HasRelated interface {
 
 type Repository[T Model] interface {
 	// Retrieves a single item from DynamoDB by key.
-	Get(ctx context.Context, key Key) (T, error)
+	// Options make the read consistent, or only read some attributes of the item; see ConsistentRead and Project.
+	Get(ctx context.Context, key Key, opts ...ReadOption) (T, error)
+
+	// BatchGet Retrieves many items from DynamoDB by key, using as many BatchGet operations as needed.
+	// Unprocessed keys are retried with exponential backoff. Models are returned in the order of the keys
+	// they were requested by, and keys that were not found are reported separately rather than failing.
+	// Options apply to every key, as in Get.
+	BatchGet(ctx context.Context, keys []Key, opts ...ReadOption) (*BatchGetResult[T], error)
+
+	// Query Retrieves a single page of items from DynamoDB that match the query.
+	// Every item is converted into a model using the repository's modeler. Options apply to every item, as in Get.
+	Query(ctx context.Context, query *Query, opts ...ReadOption) (*QueryResult[T], error)
 
 	// Create Creates a single item to DynamoDB, transactionally with its relations.
 	// Uses the Put operation to save the item, with a condition expression that asserts that the item does not yet exist.
@@ -40,7 +58,57 @@ type Repository[T Model] interface {
 
 	// Update Updates a single item to DynamoDB, transactionally with its relations.
 	// Uses the Put operation to save the item, with a condition expression that asserts that the item already exists.
+	// Models that embed Tracked and were loaded or saved through the repository are instead written with the
+	// Update operation, and only the attributes that changed since are written. Models read with Project fail with
+	// ErrPartialModel rather than overwrite an attribute that was not read.
 	Update(ctx context.Context, model T) error
+
+	// Save Writes a single item to DynamoDB whether it exists or not, transactionally with its relations.
+	// Uses the Put operation without any condition on the existence of the item, though the model's own condition
+	// expression still applies. Reports whether the item was inserted or replaced, except within a UnitOfWork.
+	// Versioned models have their version incremented, but the version they were loaded with is not asserted.
+	// Versions are never lowered though: should a newer version be stored, the item is written at the version after it.
+	Save(ctx context.Context, model T) (SaveOutcome, error)
+
+	// Changes Returns the attributes of a model that differ from the ones it was loaded or last saved with.
+	// Returns ErrNotTracked if the model does not embed Tracked, or was never loaded nor saved, and ErrPartialModel
+	// if the model was read with Project and an attribute that was not read changed.
+	Changes(model T) ([]Change, error)
+
+	// Patch Partially updates a single item in DynamoDB by key.
+	// Uses the Update operation to apply the SET, REMOVE, ADD and DELETE clauses of the update, with a condition
+	// expression that asserts that the item already exists. Attributes not touched by the update are left as-is.
+	Patch(ctx context.Context, key Key, update expression.UpdateBuilder) error
+
+	// PatchModel Partially updates a single item in DynamoDB, transactionally with its relations.
+	// The item is updated as in Patch, while its related models are saved using the Put operation.
+	PatchModel(ctx context.Context, model T, update expression.UpdateBuilder) error
+
+	// BatchPut Writes many items to DynamoDB, using as many BatchWrite operations as needed, concurrently.
+	// This is meant for bulk loads: items are written without condition expressions, related models are not
+	// written, no hooks are called, and there is no transactional guarantee. Unprocessed items are retried with
+	// exponential backoff. The report holds the outcome of every model; the returned error joins the errors of
+	// those that failed.
+	BatchPut(ctx context.Context, models []T) (*BatchWriteReport, error)
+
+	// BatchDelete Deletes many items from DynamoDB by key, as BatchPut writes them. No hooks are called.
+	BatchDelete(ctx context.Context, keys []Key) (*BatchWriteReport, error)
+
+	// Delete Deletes a single item from DynamoDB by key.
+	// Uses the Delete operation with a condition expression that asserts that the item exists.
+	// Related models are not deleted, as they cannot be derived from the key alone; use DeleteModel for that.
+	Delete(ctx context.Context, key Key) error
+
+	// DeleteModel Deletes a single item from DynamoDB, transactionally with its relations.
+	// Uses the Delete operation with a condition expression that asserts that the item exists.
+	// Versioned models also need to be at the version they were loaded with, and fail with ErrVersionConflict otherwise.
+	// Related models are deleted using their own condition expressions, if any.
+	DeleteModel(ctx context.Context, model T) error
+
+	// ConditionCheck Asserts a condition on the item identified by key, without writing it.
+	// This is mostly useful within a UnitOfWork, to make its transaction depend on an item that it does not write.
+	// Fails with ErrConditionFailed if the condition does not hold.
+	ConditionCheck(ctx context.Context, key Key, condition expression.ConditionBuilder) error
 }
 
 // Key is a map of attribute names to attribute values.
@@ -49,6 +117,47 @@ type Repository[T Model] interface {
 // based on what the model's key is.
 type Key map[string]types.AttributeValue
 
+// String returns a canonical representation of the key, with attributes in sorted order.
+// Two keys with the same attributes and values have the same representation.
+func (key Key) String() string {
+	names := make([]string, 0, len(key))
+	for k := range key {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		var value string
+		switch av := key[k].(type) {
+		case *types.AttributeValueMemberS:
+			value = "S:" + av.Value
+		case *types.AttributeValueMemberN:
+			value = "N:" + av.Value
+		case *types.AttributeValueMemberB:
+			value = "B:" + base64.StdEncoding.EncodeToString(av.Value)
+		default:
+			value = fmt.Sprintf("%T:%v", av, av)
+		}
+		parts = append(parts, strconv.Quote(k)+"="+strconv.Quote(value))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// id identifies the item of the key like String, except that numbers are identified by their value, e.g. 1.50 like
+// 1.5, so that keys match the keys of the items DynamoDB returns, whose numbers are normalized.
+func (key Key) id() string {
+	normalized := make(Key, len(key))
+	for k, v := range key {
+		if n, ok := v.(*types.AttributeValueMemberN); ok {
+			if r, ok := new(big.Rat).SetString(n.Value); ok {
+				v = &types.AttributeValueMemberN{Value: r.RatString()}
+			}
+		}
+		normalized[k] = v
+	}
+	return normalized.String()
+}
+
 // KeyValue is a helper function that constructs a string attribute value.
 func KeyValue(value string) types.AttributeValue {
 	return &types.AttributeValueMemberS{Value: value}
@@ -68,45 +177,71 @@ func (s *HasConditionExpression) ConditionExpression() *expression.Expression {
 	return s.conditionExpression
 }
 
-// Given a model's Key(), construct a condition expression that asserts that the item does not exist.
+// ConditionExpressionForCreate constructs a condition expression that asserts that the item identified by the key
+// does not exist. Every attribute of the key is asserted, in sorted order, so that the placeholders are stable.
+func (key Key) ConditionExpressionForCreate() (*expression.Expression, error) {
+	return key.buildCondition(key.notExistsCondition)
+}
+
+// CondtionExpressionForCreate constructs a condition expression that asserts that the item does not exist.
+//
+// Deprecated: use ConditionExpressionForCreate.
 func (key Key) CondtionExpressionForCreate() (*expression.Expression, error) {
-	conditions := []expression.ConditionBuilder{}
-	for k := range key {
-		conditions = append(conditions, expression.AttributeNotExists(expression.Name(k)))
-	}
+	return key.ConditionExpressionForCreate()
+}
 
-	var exprBuilder expression.Builder
-	if len(conditions) == 1 {
-		exprBuilder = expression.NewBuilder().WithCondition(conditions[0])
-	} else {
-		exprBuilder = expression.NewBuilder().WithCondition(
-			expression.And(conditions[0], conditions[1]),
-		)
+// ConditionExpressionForUpdate constructs a condition expression that asserts that the item identified by the key
+// exists. Every attribute of the key is asserted, in sorted order, so that the placeholders are stable.
+func (key Key) ConditionExpressionForUpdate() (*expression.Expression, error) {
+	return key.buildCondition(key.existsCondition)
+}
+
+// buildCondition builds the condition into an expression, failing if the key is empty.
+func (key Key) buildCondition(condition func() expression.ConditionBuilder) (*expression.Expression, error) {
+	if len(key) == 0 {
+		return nil, errors.New("key is required")
 	}
-	expr, err := exprBuilder.Build()
+	expr, err := expression.NewBuilder().WithCondition(condition()).Build()
 	if err != nil {
 		return nil, err
 	}
-	return &expr, err
+	return &expr, nil
 }
 
-// Given a model's Key(), construct a condition expression that asserts that the item does not exist.
-func (key Key) ConditionExpressionForUpdate() (*expression.Expression, error) {
-	conditions := []expression.ConditionBuilder{}
+// Attributes returns the names of the attributes of the key, sorted.
+func (key Key) Attributes() []string {
+	names := make([]string, 0, len(key))
 	for k := range key {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// existsCondition constructs a condition that asserts that the item identified by the key exists.
+// Attributes are visited in sorted order, so that the resulting placeholders are stable.
+func (key Key) existsCondition() expression.ConditionBuilder {
+	conditions := make([]expression.ConditionBuilder, 0, len(key))
+	for _, k := range key.Attributes() {
 		conditions = append(conditions, expression.AttributeExists(expression.Name(k)))
 	}
-	var exprBuilder expression.Builder
-	if len(conditions) == 1 {
-		exprBuilder = expression.NewBuilder().WithCondition(conditions[0])
-	} else {
-		exprBuilder = expression.NewBuilder().WithCondition(
-			expression.And(conditions[0], conditions[1]),
-		)
+	return andAll(conditions)
+}
+
+// notExistsCondition constructs a condition that asserts that the item identified by the key does not exist.
+// Attributes are visited in sorted order, so that the resulting placeholders are stable.
+func (key Key) notExistsCondition() expression.ConditionBuilder {
+	conditions := make([]expression.ConditionBuilder, 0, len(key))
+	for _, k := range key.Attributes() {
+		conditions = append(conditions, expression.AttributeNotExists(expression.Name(k)))
 	}
-	expr, err := exprBuilder.Build()
-	if err != nil {
-		return nil, err
+	return andAll(conditions)
+}
+
+// andAll combines one or more conditions with AND.
+func andAll(conditions []expression.ConditionBuilder) expression.ConditionBuilder {
+	if len(conditions) == 1 {
+		return conditions[0]
 	}
-	return &expr, err
+	return expression.And(conditions[0], conditions[1], conditions[2:]...)
 }
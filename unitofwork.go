@@ -0,0 +1,132 @@
+package dynamorm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// UnitOfWork collects the writes of several repositories, possibly of different tables and model types,
+// and commits them as a single transaction.
+//
+// A UnitOfWork is bound to a context with WithUnitOfWork. Create, Update, Patch, PatchModel, Delete, DeleteModel and
+// ConditionCheck called with that context register their writes with the unit of work instead of writing them,
+// and return nil unless the writes could not be constructed. Commit writes them all with TransactWriteItems, then
+// completes each operation as it would have been completed on its own: e.g. version write-backs and snapshots of
+// tracked models happen on Commit. Batch operations and reads are unaffected.
+//
+// Transactions cannot write the same item twice, nor exceed MaxTransactItems items or MaxTransactSize bytes.
+// Chunked transactions do not apply to units of work.
+type UnitOfWork struct {
+	client DynamoDBAPI
+
+	mu        sync.Mutex
+	token     string
+	writes    []write
+	after     []func()
+	hooks     []func(ctx context.Context)
+	committed bool
+}
+
+// NewUnitOfWork creates a unit of work that is committed with the given client.
+func NewUnitOfWork(client DynamoDBAPI) *UnitOfWork {
+	return &UnitOfWork{client: client}
+}
+
+// WithIdempotencyToken sets the token that the transaction is sent with, as its ClientRequestToken, so that
+// committing the same writes again with the same token does not apply them twice.
+// Without one, the token bound to the context of Commit with WithIdempotencyToken is used, if any.
+func (u *UnitOfWork) WithIdempotencyToken(token string) *UnitOfWork {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.token = token
+	return u
+}
+
+type unitOfWorkKey struct{}
+
+// WithUnitOfWork returns a context that binds repository writes to the unit of work.
+func WithUnitOfWork(ctx context.Context, uow *UnitOfWork) context.Context {
+	return context.WithValue(ctx, unitOfWorkKey{}, uow)
+}
+
+// UnitOfWorkFrom returns the unit of work bound to the context, if any.
+func UnitOfWorkFrom(ctx context.Context) (*UnitOfWork, bool) {
+	uow, ok := ctx.Value(unitOfWorkKey{}).(*UnitOfWork)
+	return uow, ok && uow != nil
+}
+
+// AfterCommit registers a hook that is called once the unit of work is committed, after the repositories
+// completed their operations. Hooks are called in the order they were registered.
+func (u *UnitOfWork) AfterCommit(hook func(ctx context.Context)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.hooks = append(u.hooks, hook)
+}
+
+// Len returns the number of items registered so far.
+func (u *UnitOfWork) Len() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.writes)
+}
+
+// register adds the items of an operation, and the function that completes it once they are written.
+func (u *UnitOfWork) register(writes []write, after func()) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.committed {
+		return ErrUnitOfWorkCommitted
+	}
+	u.writes = append(u.writes, writes...)
+	if after != nil {
+		u.after = append(u.after, after)
+	}
+	return nil
+}
+
+// Commit writes all registered items in a single transaction, then calls the post-commit hooks.
+// Cancellations are reported as a TransactionError, whose failures identify the models of every repository.
+// A unit of work can only be committed successfully once; committing it again fails with ErrUnitOfWorkCommitted.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	after, hooks, err := u.commit(ctx)
+	if err != nil {
+		return err
+	}
+	// The unit of work is unlocked by now, so that hooks can use it, e.g. call Len.
+	for _, a := range after {
+		a()
+	}
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+// commit writes all registered items and marks the unit of work as committed, returning the functions that complete
+// the operations and the post-commit hooks, for Commit to call once the lock is released.
+func (u *UnitOfWork) commit(ctx context.Context) ([]func(), []func(ctx context.Context), error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.committed {
+		return nil, nil, ErrUnitOfWorkCommitted
+	}
+	if len(u.writes) > 0 {
+		if size := transactionSize(u.writes); len(u.writes) > MaxTransactItems || size > MaxTransactSize {
+			return nil, nil, fmt.Errorf("%w: %d items, ~%d bytes", ErrTransactionTooLarge, len(u.writes), size)
+		}
+		token := u.token
+		if token == "" {
+			token, _ = IdempotencyTokenFrom(ctx)
+		}
+		if len(token) > MaxIdempotencyTokenLength {
+			return nil, nil, fmt.Errorf("idempotency token is longer than %d characters", MaxIdempotencyTokenLength)
+		}
+		if err := transactWrite(ctx, u.client, u.writes, token); err != nil {
+			return nil, nil, err
+		}
+	}
+	u.committed = true
+	return slices.Clone(u.after), slices.Clone(u.hooks), nil
+}
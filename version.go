@@ -0,0 +1,100 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Versioned is an optional interface that models can implement to opt into optimistic locking,
+// as an alternative to configuring a version attribute for all models with Builder.WithVersionAttribute.
+//
+// The version attribute holds a number that is incremented on every Create and Update. Updates and deletes of the
+// model are conditioned on the version being the one the model was loaded with, and fail with ErrVersionConflict
+// otherwise.
+// The new version is written back into the model's Item(), which therefore needs to be a pointer.
+type Versioned interface {
+	Model
+	// VersionAttribute returns the name of the version attribute. It is also called on a zero-value model when the
+	// repository is built, for operations that have no model at hand, e.g. Repository.Patch.
+	VersionAttribute() string
+}
+
+// versionAttribute returns the name of the version attribute of a model, or an empty string if it is not versioned.
+func (r *repositoryImpl[T]) versionAttribute(model Model) string {
+	if v, ok := model.(Versioned); ok {
+		return v.VersionAttribute()
+	}
+	return r.versionAttr
+}
+
+// zeroModelVersionAttribute returns the name of the version attribute of the models of T, for operations that have
+// no model at hand, e.g. Patch: the one a zero-value model returns if T is a pointer to a struct that implements
+// Versioned, otherwise the configured one. Fails if VersionAttribute() cannot be called on a zero value.
+func zeroModelVersionAttribute[T Model](configured string) (attr string, err error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct || !typ.Implements(reflect.TypeOf((*Versioned)(nil)).Elem()) {
+		return configured, nil
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("VersionAttribute() of a zero-value %v panicked: %v", typ, p)
+		}
+	}()
+	return reflect.New(typ.Elem()).Interface().(Versioned).VersionAttribute(), nil
+}
+
+// bumpVersion increments the version attribute of an item, returning the version it had.
+// Items without a version attribute are at version 0.
+func bumpVersion(item map[string]types.AttributeValue, attr string) (int64, error) {
+	current, err := itemVersion(item, attr)
+	if err != nil {
+		return 0, err
+	}
+	item[attr] = &types.AttributeValueMemberN{Value: strconv.FormatInt(current+1, 10)}
+	return current, nil
+}
+
+// itemVersion returns the version attribute of an item. Items without a version attribute are at version 0.
+func itemVersion(item map[string]types.AttributeValue, attr string) (int64, error) {
+	av, ok := item[attr]
+	if !ok {
+		return 0, nil
+	}
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("version attribute %q is not a number", attr)
+	}
+	version, err := strconv.ParseInt(n.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("version attribute %q: %w", attr, err)
+	}
+	return version, nil
+}
+
+// versionCondition asserts that the version attribute of the stored item is the given version.
+func versionCondition(attr string, version int64) expression.ConditionBuilder {
+	if version == 0 {
+		return expression.AttributeNotExists(expression.Name(attr))
+	}
+	return expression.Name(attr).Equal(expression.Value(version))
+}
+
+// versionBelow asserts that the version attribute of the stored item, if any, is lower than the given version, so
+// that writing the version does not lower it.
+func versionBelow(attr string, version int64) expression.ConditionBuilder {
+	return expression.Or(
+		expression.AttributeNotExists(expression.Name(attr)),
+		expression.Name(attr).LessThan(expression.Value(version)),
+	)
+}
+
+// writeBackVersion sets the version attribute of the model's Item() to the version that was saved.
+// This is best-effort: it is a no-op if Item() does not return a pointer.
+func writeBackVersion(model Model, item map[string]types.AttributeValue, attr string) {
+	_ = attributevalue.UnmarshalMap(map[string]types.AttributeValue{attr: item[attr]}, model.Item())
+}
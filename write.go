@@ -0,0 +1,176 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// write is a single item of a write operation, along with the model it was derived from.
+type write struct {
+	// model The model the item was derived from. Nil for writes by key.
+	model Model
+	// primary Whether the item is the one the operation was called for, as opposed to one of its related models.
+	primary bool
+	// item The item, in the form it takes within a transaction.
+	item types.TransactWriteItem
+	// conditionErr The error that a failed condition on the item maps to.
+	conditionErr error
+	// returnOld If set, is called with the item that a single-item put replaced, which is empty if there was none.
+	returnOld func(old map[string]types.AttributeValue)
+}
+
+// commit writes the items, then calls after, if any, once they are written. A single item is written with the
+// matching single-item operation, e.g. PutItem. Several items are written with TransactWriteItems, in order.
+//
+// The AfterSave hooks of the models are called after that.
+// Within a UnitOfWork, the items are registered with it instead, and after is called once it is committed.
+func (r *repositoryImpl[T]) commit(ctx context.Context, writes []write, after func()) error {
+	complete := func() {
+		if after != nil {
+			after()
+		}
+		afterSave(ctx, writes)
+	}
+	if uow, ok := UnitOfWorkFrom(ctx); ok {
+		return uow.register(writes, complete)
+	}
+	if err := r.write(ctx, writes); err != nil {
+		return err
+	}
+	complete()
+	return nil
+}
+
+// write writes the items, outside any UnitOfWork. With an idempotency token, even a single item is written with
+// TransactWriteItems, as only it accepts one.
+func (r *repositoryImpl[T]) write(ctx context.Context, writes []write) error {
+	token, err := r.idempotencyToken(ctx)
+	if err != nil {
+		return err
+	}
+	if len(writes) == 1 && writes[0].item.ConditionCheck == nil && token == "" {
+		w := writes[0]
+		var err error
+		switch {
+		case w.item.Put != nil:
+			put := w.item.Put
+			input := &dynamodb.PutItemInput{
+				Item:                                put.Item,
+				TableName:                           put.TableName,
+				ConditionExpression:                 put.ConditionExpression,
+				ExpressionAttributeNames:            put.ExpressionAttributeNames,
+				ExpressionAttributeValues:           put.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: put.ReturnValuesOnConditionCheckFailure,
+			}
+			if w.returnOld != nil {
+				input.ReturnValues = types.ReturnValueAllOld
+			}
+			var out *dynamodb.PutItemOutput
+			out, err = r.client.PutItem(ctx, input)
+			if err == nil && w.returnOld != nil {
+				w.returnOld(out.Attributes)
+			}
+		case w.item.Update != nil:
+			update := w.item.Update
+			_, err = r.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				Key:                                 update.Key,
+				TableName:                           update.TableName,
+				UpdateExpression:                    update.UpdateExpression,
+				ConditionExpression:                 update.ConditionExpression,
+				ExpressionAttributeNames:            update.ExpressionAttributeNames,
+				ExpressionAttributeValues:           update.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: update.ReturnValuesOnConditionCheckFailure,
+			})
+		case w.item.Delete != nil:
+			del := w.item.Delete
+			_, err = r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				Key:                                 del.Key,
+				TableName:                           del.TableName,
+				ConditionExpression:                 del.ConditionExpression,
+				ExpressionAttributeNames:            del.ExpressionAttributeNames,
+				ExpressionAttributeValues:           del.ExpressionAttributeValues,
+				ReturnValuesOnConditionCheckFailure: del.ReturnValuesOnConditionCheckFailure,
+			})
+		}
+		return conditionError(w, err)
+	}
+
+	// Transactions are limited in the number of items and in size. Detect this up front rather than have
+	// DynamoDB reject the transaction, and split it if we are allowed to.
+	if size := transactionSize(writes); len(writes) > MaxTransactItems || size > MaxTransactSize {
+		if !r.chunked {
+			return fmt.Errorf("%w: %d items, ~%d bytes", ErrTransactionTooLarge, len(writes), size)
+		}
+		return r.commitSaga(ctx, writes, token)
+	}
+
+	return r.commitTransaction(ctx, writes, token)
+}
+
+// commitTransaction writes the items with TransactWriteItems, in order, with the idempotency token if any.
+func (r *repositoryImpl[T]) commitTransaction(ctx context.Context, writes []write, token string) error {
+	return transactWrite(ctx, r.client, writes, token)
+}
+
+// transactWrite writes the items with TransactWriteItems, in order, using the given client.
+// The idempotency token, if any, is passed as the ClientRequestToken.
+func transactWrite(ctx context.Context, client DynamoDBAPI, writes []write, token string) error {
+	input := &dynamodb.TransactWriteItemsInput{
+		TransactItems: make([]types.TransactWriteItem, 0, len(writes)),
+	}
+	if token != "" {
+		input.ClientRequestToken = &token
+	}
+	for _, w := range writes {
+		input.TransactItems = append(input.TransactItems, w.item)
+	}
+	_, err := client.TransactWriteItems(ctx, input)
+	return idempotencyError(transactionError(writes, err))
+}
+
+// conditionError maps a failed condition on a single-item write to the error of the item, e.g. ErrAlreadyExists.
+// The original error remains in the chain.
+func conditionError(w write, err error) error {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) && w.conditionErr != nil {
+		return fmt.Errorf("%w: %w", w.conditionErr, err)
+	}
+	return err
+}
+
+// transactionError maps a canceled transaction to a TransactionError, identifying the models that caused it.
+func transactionError(writes []write, err error) error {
+	var tce *types.TransactionCanceledException
+	if !errors.As(err, &tce) {
+		return err
+	}
+	txErr := &TransactionError{Err: err}
+	for i, reason := range tce.CancellationReasons {
+		code := ""
+		if reason.Code != nil {
+			code = *reason.Code
+		}
+		// Reasons are positional; items that did not cause the cancellation have the "None" code.
+		if code == "" || code == "None" || i >= len(writes) {
+			continue
+		}
+		failure := TransactionFailure{
+			Index:   i,
+			Model:   writes[i].model,
+			Primary: writes[i].primary,
+			Code:    code,
+		}
+		if reason.Message != nil {
+			failure.Message = *reason.Message
+		}
+		if code == "ConditionalCheckFailed" {
+			failure.Err = writes[i].conditionErr
+		}
+		txErr.Failures = append(txErr.Failures, failure)
+	}
+	return txErr
+}